@@ -12,28 +12,24 @@ import (
 	//"github.com/henderiw-k8s-lcnc/discovery/registrator"
 	"github.com/pkg/profile"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
-	"github.com/yndd/lcnc-runtime/pkg/builder"
-	"github.com/yndd/lcnc-runtime/pkg/controller"
-	"github.com/yndd/lcnc-runtime/pkg/controllers/reconciler"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax/render"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
+	"github.com/yndd/lcnc-runtime/pkg/controllers/controllerconfig"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"go.uber.org/zap/zapcore"
 
 	//"github.com/yndd/lcnc-runtime/pkg/pcache"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
-	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
 	"github.com/yndd/lcnc-runtime/pkg/manager"
 
 	"github.com/containers/podman/v4/pkg/rootless"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
-	"sigs.k8s.io/yaml"
 )
 
-// const yamlFile = "./examples/upf.yaml"
-const yamlFile = "./examples/topo4.yaml"
-
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
@@ -41,14 +37,32 @@ func main() {
 	var debug bool
 	var profiler bool
 	var concurrency int
+	var maxWorkers int
+	var maxContainerFns int
+	var allowWasm bool
 	var pollInterval time.Duration
+	var enableWebhook bool
+	var webhookPort int
+	var certDir string
+	var renderFile string
+	var renderFormat string
+	var renderOutput string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.IntVar(&concurrency, "concurrency", 1, "Number of items to process simultaneously")
+	flag.IntVar(&maxWorkers, "max-workers", executor.DefaultMaxWorkers, "The maximum number of functions that run concurrently in a pipeline.")
+	flag.IntVar(&maxContainerFns, "max-container-fns", fnruntime.DefaultMaxConcurrentContainerFns, "The maximum number of container functions that run concurrently across all controllers, 0 is unlimited.")
+	flag.BoolVar(&allowWasm, "allow-wasm", false, "Allow wasm functions, which run in process, to be executed.")
 	flag.DurationVar(&pollInterval, "poll-interval", 1*time.Minute, "Poll interval controls how often an individual resource should be checked for drift.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false, "Enable the validating webhook for ControllerConfig resources.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server serves at.")
+	flag.StringVar(&certDir, "cert-dir", "", "The directory that contains the webhook server key and certificate.")
+	flag.StringVar(&renderFile, "render", "", "Render the DAGs of the ControllerConfig in the file and exit.")
+	flag.StringVar(&renderFormat, "render-format", string(render.FormatDOT), "The format the DAGs are rendered in: dot, mermaid or json.")
+	flag.StringVar(&renderOutput, "render-output", "", "The file the rendered DAGs are written to, defaults to <name>.<extension of the format>.")
 	flag.BoolVar(&debug, "debug", true, "Enable debug")
 	flag.BoolVar(&profiler, "profile", false, "Enable profiler")
 	opts := zap.Options{
@@ -61,6 +75,14 @@ func main() {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 	l := ctrl.Log.WithName("lcnc runtime")
 
+	if renderFile != "" {
+		if err := renderDAGs(renderFile, render.Format(renderFormat), renderOutput); err != nil {
+			l.Error(err, "cannot render controller config", "file", renderFile)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if profiler {
 		defer profile.Start().Stop()
 		go func() {
@@ -68,82 +90,51 @@ func main() {
 		}()
 	}
 
-	mgr, err := manager.New(ctrl.GetConfigOrDie(), manager.Options{
-		Namespace:              os.Getenv("POD_NAMESPACE"),
-		HealthProbeBindAddress: probeAddr,
-	})
-	if err != nil {
-		l.Error(err, "unable to create manager")
-		os.Exit(1)
-	}
-
-	fb, err := os.ReadFile(yamlFile)
-	if err != nil {
-		l.Error(err, "cannot read file")
-		os.Exit(1)
-	}
-	l.Info("read file")
+	fnruntime.SetMaxConcurrentContainerFns(maxContainerFns)
 
-	ctrlcfg := &ctrlcfgv1.ControllerConfig{}
-	if err := yaml.Unmarshal(fb, ctrlcfg); err != nil {
-		l.Error(err, "cannot unmarshal")
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		l.Error(err, "cannot add client go scheme")
 		os.Exit(1)
 	}
-	l.Info("unmarshal succeeded")
-
-	p, result := ccsyntax.NewParser(ctrlcfg)
-	if len(result) > 0 {
-		l.Error(err, "ccsyntax validation failed", "result", result)
+	if err := ctrlcfgv1.AddToScheme(scheme); err != nil {
+		l.Error(err, "cannot add controllerconfig scheme")
 		os.Exit(1)
 	}
-	l.Info("ccsyntax validation succeeded")
 
-	ceCtx, result := p.Parse()
-	if len(result) != 0 {
-		for _, res := range result {
-			l.Error(err, "ccsyntax parsing failed", "result", res)
-		}
+	mgr, err := manager.New(ctrl.GetConfigOrDie(), manager.Options{
+		Scheme:                 scheme,
+		Namespace:              os.Getenv("POD_NAMESPACE"),
+		HealthProbeBindAddress: probeAddr,
+		Port:                   webhookPort,
+		CertDir:                certDir,
+	})
+	if err != nil {
+		l.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
-	l.Info("ccsyntax parsing succeeded")
 
-	gvks, result := p.GetExternalResources()
-	if len(result) > 0 {
-		l.Error(err, "ccsyntax get external resources failed", "result", result)
+	ctx := ctrl.SetupSignalHandler()
+	if err := controllerconfig.Setup(ctx, &controllerconfig.Config{
+		Mgr:                     mgr,
+		PollInterval:            pollInterval,
+		MaxConcurrentReconciles: 8,
+		MaxWorkers:              maxWorkers,
+		AllowWasm:               allowWasm,
+	}); err != nil {
+		l.Error(err, "cannot setup controllerconfig controller")
 		os.Exit(1)
 	}
+	l.Info("setup controller")
 
-	// validate if we can resolve the gvr to gvk in the system
-	for _, gvk := range gvks {
-		gvk, err := mgr.GetRESTMapper().RESTMapping(schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}, gvk.Version)
-		if err != nil {
-			l.Error(err, "ccsyntax get gvk mapping in api server", "result", result)
+	if enableWebhook {
+		controllerconfig.SetupWebhook(mgr)
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			l.Error(err, "unable to set up webhook ready check")
 			os.Exit(1)
 		}
-		l.Info("gvk", "value", gvk)
+		l.Info("setup webhook")
 	}
-
-	ge := make(chan event.GenericEvent)
-
-	b := builder.New(&builder.Config{
-		Mgr:          mgr,
-		CeCtx:        ceCtx,
-		GenericEvent: ge,
-	}, controller.Options{
-		MaxConcurrentReconciles: 8,
-	})
-	_, err = b.Build(reconciler.New(&reconciler.Config{
-		Client:       mgr.GetClient(),
-		PollInterval: 1 * time.Minute,
-		CeCtx:        ceCtx,
-	}))
-	if err != nil {
-		l.Error(err, "cannot build controller")
-		os.Exit(1)
-	}
-
-	l.Info("setup controller")
-	ctx := ctrl.SetupSignalHandler()
 	/*
 		reg, err := registrator.New(ctx, ctrl.GetConfigOrDie(), &registrator.Options{
 			ServiceDiscovery:          discovery.ServiceDiscoveryTypeK8s,
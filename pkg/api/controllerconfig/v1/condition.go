@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType of the ControllerConfig
+type ConditionType string
+
+const (
+	// ConditionTypeReady indicates the ControllerConfig is parsed and its
+	// controller is running the latest generation
+	ConditionTypeReady ConditionType = "Ready"
+	// ConditionTypeParsed indicates the latest generation of the
+	// ControllerConfig was parsed successfully
+	ConditionTypeParsed ConditionType = "Parsed"
+	// ConditionTypeRunning indicates a controller is running for the
+	// ControllerConfig
+	ConditionTypeRunning ConditionType = "Running"
+)
+
+// ConditionReason of the ControllerConfig
+type ConditionReason string
+
+const (
+	ConditionReasonAvailable           ConditionReason = "Available"
+	ConditionReasonUnavailable         ConditionReason = "Unavailable"
+	ConditionReasonParseSucceeded      ConditionReason = "ParseSucceeded"
+	ConditionReasonSyntaxError         ConditionReason = "SyntaxError"
+	ConditionReasonParseError          ConditionReason = "ParseError"
+	ConditionReasonUnresolvedResources ConditionReason = "UnresolvedResources"
+	ConditionReasonStarted             ConditionReason = "Started"
+	ConditionReasonBuildFailed         ConditionReason = "BuildFailed"
+	ConditionReasonOutdated            ConditionReason = "Outdated"
+)
+
+// SetCondition sets the condition on the ControllerConfig status,
+// the transition time only changes when the status changes
+func (r *ControllerConfig) SetCondition(t ConditionType, status metav1.ConditionStatus, reason ConditionReason, msg string) {
+	meta.SetStatusCondition(&r.Status.Conditions, metav1.Condition{
+		Type:               string(t),
+		Status:             status,
+		ObservedGeneration: r.GetGeneration(),
+		Reason:             string(reason),
+		Message:            msg,
+	})
+}
+
+// GetCondition returns the condition of the given type, nil if not found
+func (r *ControllerConfig) GetCondition(t ConditionType) *metav1.Condition {
+	return meta.FindStatusCondition(r.Status.Conditions, string(t))
+}
+
+// IsConditionTrue returns true if the condition of the given type is true
+func (r *ControllerConfig) IsConditionTrue(t ConditionType) bool {
+	return meta.IsStatusConditionTrue(r.Status.Conditions, string(t))
+}
@@ -60,6 +60,23 @@ func (r *ControllerConfig) GetWatchGvks() ([]*schema.GroupVersionKind, error) {
 	return gvks, nil
 }
 
+// GetNoPruneGvks returns the gvks of the own resources that are not pruned
+func (r *ControllerConfig) GetNoPruneGvks() ([]*schema.GroupVersionKind, error) {
+	noPrune := map[string]*GvkObject{}
+	for name, gvrObj := range r.Spec.Properties.Own {
+		if gvrObj.Prune != nil && !*gvrObj.Prune {
+			noPrune[name] = gvrObj
+		}
+	}
+	return r.getGvkList(noPrune)
+}
+
+// IsPruneDryRun returns true when the resources that would be pruned are
+// only logged
+func (r *ControllerConfig) IsPruneDryRun() bool {
+	return r.Spec.Prune != nil && r.Spec.Prune.DryRun
+}
+
 func (r *ControllerConfig) getGvkList(gvrObjs map[string]*GvkObject) ([]*schema.GroupVersionKind, error) {
 	gvks := make([]*schema.GroupVersionKind, 0, len(gvrObjs))
 	for _, gvrObj := range gvrObjs {
@@ -100,6 +117,40 @@ func GetGVK(gvr runtime.RawExtension) (*schema.GroupVersionKind, error) {
 	}, nil
 }
 
+// GetQueryExpressions returns the jq expressions of the namespace, the label
+// and field selectors, the get and the owner of the input
+func (v *Input) GetQueryExpressions() []string {
+	exps := []string{}
+	if IsExpression(v.Namespace) {
+		exps = append(exps, v.Namespace)
+	}
+	if v.Get != nil && IsExpression(v.Get.Name) {
+		exps = append(exps, v.Get.Name)
+	}
+	if IsExpression(v.OwnedBy) {
+		exps = append(exps, v.OwnedBy)
+	}
+	if v.Selector != nil {
+		for _, val := range v.Selector.MatchLabels {
+			if IsExpression(val) {
+				exps = append(exps, val)
+			}
+		}
+	}
+	for _, val := range v.FieldSelector {
+		if IsExpression(val) {
+			exps = append(exps, val)
+		}
+	}
+	return exps
+}
+
+// IsExpression returns true when the value is a jq expression, which starts
+// with a variable reference, otherwise the value is a literal
+func IsExpression(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "$")
+}
+
 func (v *Function) HasBlock() bool {
 	return v.Block.Range != nil || v.Block.Condition != nil
 }
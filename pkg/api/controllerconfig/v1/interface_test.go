@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("V1", func() {
+	Describe("GetQueryExpressions", func() {
+		It("should return no expressions for an empty input", func() {
+			in := &ctrlcfgv1.Input{}
+			Expect(in.GetQueryExpressions()).To(BeEmpty())
+		})
+
+		It("should return no expressions for literal values", func() {
+			in := &ctrlcfgv1.Input{
+				Namespace:     "default",
+				Get:           &ctrlcfgv1.QueryGet{Name: "test"},
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				FieldSelector: map[string]string{"spec.nodeName": "node1"},
+			}
+			Expect(in.GetQueryExpressions()).To(BeEmpty())
+		})
+
+		It("should return the expression of the namespace", func() {
+			in := &ctrlcfgv1.Input{Namespace: "$cr.metadata.namespace"}
+			Expect(in.GetQueryExpressions()).To(ConsistOf("$cr.metadata.namespace"))
+		})
+
+		It("should return the expression of the name of a get", func() {
+			in := &ctrlcfgv1.Input{Get: &ctrlcfgv1.QueryGet{Name: " $cr.spec.name"}}
+			Expect(in.GetQueryExpressions()).To(ConsistOf(" $cr.spec.name"))
+		})
+
+		It("should return the expression of the owner", func() {
+			in := &ctrlcfgv1.Input{OwnedBy: "$cr"}
+			Expect(in.GetQueryExpressions()).To(ConsistOf("$cr"))
+		})
+
+		It("should return the expressions of the label and field selectors", func() {
+			in := &ctrlcfgv1.Input{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{
+					"app":  "$cr.metadata.name",
+					"tier": "web",
+				}},
+				FieldSelector: map[string]string{
+					"spec.nodeName": "$node.metadata.name",
+					"status.phase":  "Running",
+				},
+			}
+			Expect(in.GetQueryExpressions()).To(ConsistOf("$cr.metadata.name", "$node.metadata.name"))
+		})
+	})
+
+	Describe("IsExpression", func() {
+		It("should return false for an empty value", func() {
+			Expect(ctrlcfgv1.IsExpression("")).To(BeFalse())
+		})
+
+		It("should return false for a literal value", func() {
+			Expect(ctrlcfgv1.IsExpression("default")).To(BeFalse())
+		})
+
+		It("should return true for a variable", func() {
+			Expect(ctrlcfgv1.IsExpression("$cr.metadata.namespace")).To(BeTrue())
+		})
+
+		It("should return true for a variable with leading spaces", func() {
+			Expect(ctrlcfgv1.IsExpression("  $cr")).To(BeTrue())
+		})
+	})
+})
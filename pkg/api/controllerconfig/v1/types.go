@@ -31,6 +31,11 @@ var (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=ccfg
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="PARSED",type="string",JSONPath=".status.conditions[?(@.type=='Parsed')].status"
+// +kubebuilder:printcolumn:name="RUNNING",type="string",JSONPath=".status.conditions[?(@.type=='Running')].status"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 type ControllerConfig struct {
 	metav1.TypeMeta   `json:",inline" yaml:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
@@ -40,9 +45,21 @@ type ControllerConfig struct {
 }
 
 type Spec struct {
+	// PollInterval overrides how often the resources of the controller are
+	// reconciled to correct drift, a zero interval disables polling. A
+	// resource overrides it with the lcnc.yndd.io/poll-interval annotation.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty" yaml:"pollInterval,omitempty"`
+	// Prune configures the deletion of the resources that were applied for
+	// a resource and are no longer part of the pipeline output
+	Prune      *Prune      `json:"prune,omitempty" yaml:"prune,omitempty"`
 	Properties *Properties `json:"properties,omitempty" yaml:"properties,omitempty"`
 }
 
+type Prune struct {
+	// DryRun logs the resources that would be pruned instead of deleting them
+	DryRun bool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+}
+
 type Properties struct {
 	// key represents the variable
 	For map[string]*GvkObject `json:"for" yaml:"for"`
@@ -61,8 +78,46 @@ type GvkObject struct {
 	Resource          runtime.RawExtension `json:"resource,omitempty" yaml:"resource,omitempty"`
 	ApplyPipelineRef  string               `json:"applyPipelineRef,omitempty" yaml:"applyPipelineRef,omitempty"`
 	DeletePipelineRef string               `json:"deletePipelineRef,omitempty" yaml:"deletePipelineRef,omitempty"`
+	// Prune deletes the own resources of this gvk that are no longer part
+	// of the pipeline output, it defaults to true
+	Prune *bool `json:"prune,omitempty" yaml:"prune,omitempty"`
+	// Predicates filter the events of the resources of this gvk, all the
+	// events trigger the pipeline when not set
+	Predicates *Predicates `json:"predicates,omitempty" yaml:"predicates,omitempty"`
+}
+
+// Predicates filter the events of a resource, an event triggers the pipeline
+// when it passes all the predicates
+type Predicates struct {
+	// GenerationChanged ignores the updates that do not change the
+	// generation of the resource, such as status updates
+	GenerationChanged bool `json:"generationChanged,omitempty" yaml:"generationChanged,omitempty"`
+	// LabelSelector selects the resources by label
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty" yaml:"labelSelector,omitempty"`
+	// AnnotationSelector selects the resources by annotation, the
+	// annotations are matched like labels
+	AnnotationSelector *metav1.LabelSelector `json:"annotationSelector,omitempty" yaml:"annotationSelector,omitempty"`
+	// NamespaceSelector selects the resources by the labels of their
+	// namespace, cluster scoped resources are not filtered
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty" yaml:"namespaceSelector,omitempty"`
+	// EventTypes are the types of the events that pass, the events of all
+	// types pass when not set
+	EventTypes []EventType `json:"eventTypes,omitempty" yaml:"eventTypes,omitempty"`
+	// Expression is a jq expression that must result in true for the event
+	// to pass, the resource before and after the event are available as
+	// $old and $new. $old is null for a create and $new is null for a delete.
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=create;update;delete
+type EventType string
+
+const (
+	EventTypeCreate EventType = "create"
+	EventTypeUpdate EventType = "update"
+	EventTypeDelete EventType = "delete"
+)
+
 type Pipeline struct {
 	Name  string                      `json:"name" yaml:"name"`
 	Vars  map[string]*FunctionElement `json:"vars,omitempty" yaml:"vars,omitempty"`
@@ -76,9 +131,25 @@ type Block struct {
 
 type RangeValue struct {
 	Value string `json:"value" yaml:"value"`
-	Block `json:",inline" yaml:",inline"`
+	// Concurrency is the maximum number of range items that are executed in
+	// parallel, 0 and 1 execute the items sequentially
+	// +kubebuilder:validation:Minimum=0
+	Concurrency int `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	// ErrorPolicy defines how errors of the range items are handled, failFast
+	// stops the range at the first error, collect executes all items and
+	// returns all the errors. The default is failFast.
+	// +kubebuilder:validation:Enum=failFast;collect
+	ErrorPolicy RangeErrorPolicy `json:"errorPolicy,omitempty" yaml:"errorPolicy,omitempty"`
+	Block       `json:",inline" yaml:",inline"`
 }
 
+type RangeErrorPolicy string
+
+const (
+	RangeErrorPolicyFailFast RangeErrorPolicy = "failFast"
+	RangeErrorPolicyCollect  RangeErrorPolicy = "collect"
+)
+
 type ConditionExpression struct {
 	Expression string `json:"expression" yaml:"expression"`
 	Block      `json:",inline" yaml:",inline"`
@@ -117,22 +188,77 @@ type Function struct {
 	// key = variableName, value is gvr format or not -> gvr format is needed for external resources
 	Output    map[string]*Output `json:"output,omitempty" yaml:"output,omitempty"`
 	DependsOn []string           `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	// Timeout is the maximum duration of a single attempt of the function,
+	// when not set the attempt is not bounded in time
+	Timeout *metav1.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Retries is the amount of times the function is retried after a failed
+	// attempt
+	// +kubebuilder:validation:Minimum=0
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Backoff defines the delay between the attempts of the function
+	Backoff *Backoff `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+}
+
+// Backoff defines an exponential delay between the attempts of a function
+type Backoff struct {
+	// Duration is the delay before the first retry, defaults to 1s
+	Duration *metav1.Duration `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// Factor multiplies the delay after every retry, defaults to 2
+	// +kubebuilder:validation:Minimum=1
+	Factor int `json:"factor,omitempty" yaml:"factor,omitempty"`
+	// Max is the maximum delay between attempts, defaults to 30s
+	Max *metav1.Duration `json:"max,omitempty" yaml:"max,omitempty"`
 }
 
 type Output struct {
 	Internal    bool                 `json:"internal" yaml:"internal"`
 	Conditioned bool                 `json:"conditioned" yaml:"conditioned"`
 	Resource    runtime.RawExtension `json:"resource" yaml:"resource"`
+	// ForceConflicts takes over the fields of the output resources that are
+	// managed by another field manager, by default a conflict fails the
+	// apply of the resource and is reported in the status of the for resource
+	ForceConflicts bool `json:"forceConflicts,omitempty" yaml:"forceConflicts,omitempty"`
 }
 
 type Input struct {
-	Selector     *metav1.LabelSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
-	Key          string                `json:"key,omitempty" yaml:"key,omitempty"`
-	Value        string                `json:"value,omitempty" yaml:"value,omitempty"`
-	GenericInput map[string]string     `json:",inline" yaml:",inline"`
-	Expression   string                `json:"expression,omitempty" yaml:"expression,omitempty"`
-	Resource     runtime.RawExtension  `json:"resource,omitempty" yaml:"resource,omitempty"`
-	Template     string                `json:"template,omitempty" yaml:"template,omitempty"`
+	// Selector selects the objects of a query by label, the values of
+	// matchLabels are jq expressions when they start with a $ and literal
+	// values otherwise
+	Selector *metav1.LabelSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
+	// FieldSelector selects the objects of a query by field, the key is the
+	// field path and the value is a jq expression when it starts with a $ and
+	// a literal value otherwise
+	FieldSelector map[string]string `json:"fieldSelector,omitempty" yaml:"fieldSelector,omitempty"`
+	// Namespace restricts a query to a namespace, the value is a jq
+	// expression when it starts with a $ and a literal value otherwise.
+	// When not set the query lists the objects in all namespaces.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Get fetches a single object of a query by name instead of listing the
+	// objects, the namespace of the object is supplied by Namespace
+	Get *QueryGet `json:"get,omitempty" yaml:"get,omitempty"`
+	// OwnedBy restricts a query to the objects that are controlled by an
+	// owner, it is a jq expression resulting in the owner object e.g. the
+	// for object
+	OwnedBy string `json:"ownedBy,omitempty" yaml:"ownedBy,omitempty"`
+	// Live reads the objects of a query from the api server instead of the
+	// informer cache, lists are read in pages. Queries with a field selector
+	// are always read live as the cache only supports indexed fields.
+	Live         bool                 `json:"live,omitempty" yaml:"live,omitempty"`
+	Key          string               `json:"key,omitempty" yaml:"key,omitempty"`
+	Value        string               `json:"value,omitempty" yaml:"value,omitempty"`
+	GenericInput map[string]string    `json:",inline" yaml:",inline"`
+	Expression   string               `json:"expression,omitempty" yaml:"expression,omitempty"`
+	Resource     runtime.RawExtension `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Template     string               `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+type QueryGet struct {
+	// Name of the object, the value is a jq expression when it starts with
+	// a $ and a literal value otherwise
+	Name string `json:"name" yaml:"name"`
+	// FailIfNotFound fails the query when the object does not exist, by
+	// default the result of the query is null
+	FailIfNotFound bool `json:"failIfNotFound,omitempty" yaml:"failIfNotFound,omitempty"`
 }
 
 type Executor struct {
@@ -140,8 +266,57 @@ type Executor struct {
 	Exec  string `json:"exec,omitempty" yaml:"exec,omitempty"`
 }
 
-// ResourceContextSpec defines the context of the resource of the controller
+// Status defines the observed state of the ControllerConfig
 type Status struct {
+	// ObservedGeneration is the generation of the ControllerConfig
+	// the status was computed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"`
+	// Conditions of the ControllerConfig: Ready, Parsed and Running
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	// Resources are the resolved for, own and watch resources
+	Resources *ResourcesStatus `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// Pipelines summarizes the compiled pipelines
+	Pipelines []PipelineStatus `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	// ParseErrors are the errors found while validating and parsing the
+	// ControllerConfig
+	ParseErrors []ParseError `json:"parseErrors,omitempty" yaml:"parseErrors,omitempty"`
+}
+
+type ResourcesStatus struct {
+	For   []metav1.GroupVersionKind `json:"for,omitempty" yaml:"for,omitempty"`
+	Own   []metav1.GroupVersionKind `json:"own,omitempty" yaml:"own,omitempty"`
+	Watch []metav1.GroupVersionKind `json:"watch,omitempty" yaml:"watch,omitempty"`
+}
+
+type PipelineStatus struct {
+	// Name of the pipeline
+	Name string `json:"name" yaml:"name"`
+	// FOW indicates if the pipeline runs for the for or a watch resource
+	FOW string `json:"fow" yaml:"fow"`
+	// Resource the pipeline runs for
+	Resource metav1.GroupVersionKind `json:"resource" yaml:"resource"`
+	// Operation is apply or delete
+	Operation string `json:"operation" yaml:"operation"`
+	// Vertices is the number of vertices in the pipeline dag
+	Vertices int `json:"vertices" yaml:"vertices"`
+	// BlockVertices is the number of vertices per block dag, the key is
+	// the name of the block vertex prefixed with the names of the blocks
+	// it is nested in, separated by a dot
+	BlockVertices map[string]int `json:"blockVertices,omitempty" yaml:"blockVertices,omitempty"`
+}
+
+// ParseError is a structured error reported by the parser
+type ParseError struct {
+	FOW             string `json:"fow,omitempty" yaml:"fow,omitempty"`
+	Resource        string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Operation       string `json:"operation,omitempty" yaml:"operation,omitempty"`
+	Pipeline        string `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	BlockVertexName string `json:"blockVertexName,omitempty" yaml:"blockVertexName,omitempty"`
+	VertexName      string `json:"vertexName,omitempty" yaml:"vertexName,omitempty"`
+	LocalVarName    string `json:"localVarName,omitempty" yaml:"localVarName,omitempty"`
+	Message         string `json:"message" yaml:"message"`
 }
 
 // ControllerConfigList
@@ -0,0 +1,13 @@
+package applicator
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestApplicator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Applicator Suite")
+}
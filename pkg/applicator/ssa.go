@@ -0,0 +1,53 @@
+package applicator
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// An APIServerSideApplicator applies changes to an object with server-side
+// apply in a Kubernetes API server. The fields of the object are owned by the
+// field manager of the applicator, fields that are no longer applied are
+// removed and fields owned by other field managers are left untouched.
+type APIServerSideApplicator struct {
+	client       client.Client
+	fieldManager string
+	force        bool
+}
+
+// NewAPIServerSideApplicator returns an Applicator that applies changes to an
+// object with server-side apply as the supplied field manager. When force is
+// set the applicator takes over the fields that are managed by another field
+// manager, otherwise such a conflict fails the apply.
+func NewAPIServerSideApplicator(c client.Client, fieldManager string, force bool) *APIServerSideApplicator {
+	return &APIServerSideApplicator{client: c, fieldManager: fieldManager, force: force}
+}
+
+// Apply the supplied object with server-side apply. The object will be created
+// if it does not exist. The ApplyOptions are not used as the current object is
+// merged with the object by the API server.
+func (a *APIServerSideApplicator) Apply(ctx context.Context, o client.Object, _ ...ApplyOption) error {
+	m, ok := o.(metav1.Object)
+	if !ok {
+		return errors.New("cannot access object metadata")
+	}
+
+	// server-side apply needs a name
+	if m.GetName() == "" && m.GetGenerateName() != "" {
+		return errors.Wrap(a.client.Create(ctx, o), "cannot create object")
+	}
+
+	// the managed fields cannot be applied and a resource version would make
+	// the apply fail when the object changed in the meantime
+	m.SetManagedFields(nil)
+	m.SetResourceVersion("")
+
+	opts := []client.PatchOption{client.FieldOwner(a.fieldManager)}
+	if a.force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return errors.Wrap(a.client.Patch(ctx, o, client.Apply, opts...), "cannot apply object")
+}
@@ -0,0 +1,95 @@
+package applicator
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// patchClient records the patches and the creates, the patch returns the
+// configured error
+type patchClient struct {
+	client.Client
+	err     error
+	patched []client.Object
+	patches []client.Patch
+	opts    []*client.PatchOptions
+	created []client.Object
+}
+
+func (c *patchClient) Patch(_ context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	o := &client.PatchOptions{}
+	o.ApplyOptions(opts)
+	c.patched = append(c.patched, obj)
+	c.patches = append(c.patches, patch)
+	c.opts = append(c.opts, o)
+	return c.err
+}
+
+func (c *patchClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.created = append(c.created, obj)
+	return nil
+}
+
+func newConfigMap(name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("ConfigMap")
+	u.SetNamespace("default")
+	u.SetName(name)
+	return u
+}
+
+var _ = Describe("Applicator", func() {
+	Describe("APIServerSideApplicator", func() {
+		var c *patchClient
+
+		BeforeEach(func() {
+			c = &patchClient{}
+		})
+
+		It("should apply the object as the field manager", func() {
+			u := newConfigMap("a")
+			Expect(NewAPIServerSideApplicator(c, "test", false).Apply(context.Background(), u)).To(Succeed())
+			Expect(c.patched).To(Equal([]client.Object{u}))
+			Expect(c.patches).To(Equal([]client.Patch{client.Apply}))
+			Expect(c.opts[0].FieldManager).To(Equal("test"))
+			Expect(c.opts[0].Force).To(BeNil())
+		})
+
+		It("should force the ownership of conflicting fields", func() {
+			Expect(NewAPIServerSideApplicator(c, "test", true).Apply(context.Background(), newConfigMap("a"))).To(Succeed())
+			Expect(c.opts[0].Force).To(HaveValue(BeTrue()))
+		})
+
+		It("should clear the managed fields and the resource version", func() {
+			u := newConfigMap("a")
+			u.SetResourceVersion("1")
+			u.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "other"}})
+			Expect(NewAPIServerSideApplicator(c, "test", false).Apply(context.Background(), u)).To(Succeed())
+			Expect(u.GetResourceVersion()).To(BeEmpty())
+			Expect(u.GetManagedFields()).To(BeEmpty())
+		})
+
+		It("should create an object with a generated name", func() {
+			u := newConfigMap("")
+			u.SetGenerateName("a-")
+			Expect(NewAPIServerSideApplicator(c, "test", false).Apply(context.Background(), u)).To(Succeed())
+			Expect(c.created).To(Equal([]client.Object{u}))
+			Expect(c.patched).To(BeEmpty())
+		})
+
+		It("should keep the conflict error", func() {
+			c.err = apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "a", nil)
+			err := NewAPIServerSideApplicator(c, "test", false).Apply(context.Background(), newConfigMap("a"))
+			Expect(err).To(MatchError(ContainSubstring("cannot apply object")))
+			Expect(apierrors.IsConflict(err)).To(BeTrue())
+		})
+	})
+})
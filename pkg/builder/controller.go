@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/yndd/lcnc-runtime/pkg/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -20,15 +22,21 @@ import (
 )
 
 var newController = controller.New
+var newUnmanagedController = controller.NewUnmanaged
 
 type Builder interface {
 	Build(r reconcile.Reconciler) (controller.Controller, error)
 }
 
 type builder struct {
+	ctx   context.Context
 	mgr   manager.Manager
 	ceCtx ccsyntax.ConfigExecutionContext
 	ge    chan event.GenericEvent
+	// unmanaged controllers are not added to the manager
+	unmanaged  bool
+	maxWorkers int
+	allowWasm  bool
 
 	globalPredicates []predicate.Predicate
 	ctrl             controller.Controller
@@ -36,16 +44,35 @@ type builder struct {
 }
 
 type Config struct {
+	// Ctx is the context the watch pipelines run with, it should be
+	// cancelled when the controller stops
+	Ctx          context.Context
 	Mgr          manager.Manager
 	CeCtx        ccsyntax.ConfigExecutionContext
 	GenericEvent chan event.GenericEvent
+	// Unmanaged builds the controller without adding it to the manager,
+	// the caller is responsible for starting and stopping the controller
+	Unmanaged bool
+	// MaxWorkers is the maximum number of functions that run concurrently
+	// in the watch pipelines
+	MaxWorkers int
+	// AllowWasm enables wasm functions in the watch pipelines
+	AllowWasm bool
 }
 
 func New(c *Config, opts controller.Options) Builder {
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	b := &builder{
+		ctx:         ctx,
 		mgr:         c.Mgr,
 		ceCtx:       c.CeCtx,
 		ge:          c.GenericEvent,
+		unmanaged:   c.Unmanaged,
+		maxWorkers:  c.MaxWorkers,
+		allowWasm:   c.AllowWasm,
 		ctrlOptions: opts,
 	}
 	return b
@@ -77,12 +104,16 @@ func (blder *builder) doWatch() error {
 	typeForSrc := meta.GetUnstructuredFromGVK(gvk)
 	src := &source.Kind{Type: typeForSrc}
 	hdler := &handler.EnqueueRequestForObject{}
-	allPredicates := append(blder.globalPredicates, []predicate.Predicate{}...)
+	allPredicates, err := blder.getPredicates(ccsyntax.FOWFor, gvk)
+	if err != nil {
+		return err
+	}
 	if err := blder.ctrl.Watch(src, hdler, allPredicates...); err != nil {
 		return err
 	}
-	// add the generic event watch to the for object
-	if err := blder.ctrl.Watch(&source.Channel{Source: blder.ge}, hdler, allPredicates...); err != nil {
+	// add the generic event watch to the for object, the predicates of the
+	// for resource do not apply to the generic events
+	if err := blder.ctrl.Watch(&source.Channel{Source: blder.ge}, hdler, blder.globalPredicates...); err != nil {
 		return err
 	}
 
@@ -96,29 +127,47 @@ func (blder *builder) doWatch() error {
 			OwnerType:    typeForSrc,
 			IsController: true,
 		}
-		allPredicates := append([]predicate.Predicate(nil), blder.globalPredicates...)
-		allPredicates = append(allPredicates, []predicate.Predicate{}...)
+		allPredicates, err := blder.getPredicates(ccsyntax.FOWOwn, &gvk)
+		if err != nil {
+			return err
+		}
 		if err := blder.ctrl.Watch(src, hdler, allPredicates...); err != nil {
 			return err
 		}
+		// resources that cannot have an owner reference to the for resource
+		// track their owner with labels
+		if err := blder.ctrl.Watch(src, handler.EnqueueRequestsFromMapFunc(ownerLabelsMapFn(typeForSrc.GroupVersionKind().GroupKind())), allPredicates...); err != nil {
+			return err
+		}
 	}
 
 	// handle Watch
 	for gvk, od := range blder.ceCtx.GetFOW(ccsyntax.FOWWatch) {
+		// the event handler keeps a pointer to the gvk, the loop variable
+		// is reused in every iteration
+		gvk := gvk
 		//var obj client.Object
 		obj := meta.GetUnstructuredFromGVK(&gvk)
 
-		allPredicates := append([]predicate.Predicate(nil), blder.globalPredicates...)
-		allPredicates = append(allPredicates, []predicate.Predicate{}...)
+		allPredicates, err := blder.getPredicates(ccsyntax.FOWWatch, &gvk)
+		if err != nil {
+			return err
+		}
 
 		// If the source of this watch is of type *source.Kind, project it.
 		src := &source.Kind{Type: obj}
 
 		eh := eventhandler.New(&eventhandler.Config{
+			Ctx:            blder.ctx,
 			Client:         blder.mgr.GetClient(),
+			Cache:          blder.mgr.GetCache(),
 			RootVertexName: od[ccsyntax.OperationApply].RootVertexName,
 			GVK:            &gvk,
+			ForGVK:         blder.ceCtx.GetForGVK(),
 			DAG:            od[ccsyntax.OperationApply].DAG,
+			Services:       blder.ceCtx.GetServices(),
+			MaxWorkers:     blder.maxWorkers,
+			AllowWasm:      blder.allowWasm,
 		})
 
 		if err := blder.ctrl.Watch(src, eh, allPredicates...); err != nil {
@@ -129,6 +178,18 @@ func (blder *builder) doWatch() error {
 	return nil
 }
 
+// ownerLabelsMapFn maps a resource to a request for the owner that is tracked
+// by the owner labels of the resource
+func ownerLabelsMapFn(gk schema.GroupKind) handler.MapFunc {
+	return func(o client.Object) []reconcile.Request {
+		nsn, ok := meta.GetOwnerFromLabels(o, gk)
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: nsn}}
+	}
+}
+
 func (blder *builder) getControllerName(gvk *schema.GroupVersionKind) string {
 	if blder.ceCtx.GetName() != "" {
 		return blder.ceCtx.GetName()
@@ -184,6 +245,10 @@ func (blder *builder) doController(r reconcile.Reconciler) error {
 	}
 	// Build the controller and return.
 	var err error
+	if blder.unmanaged {
+		blder.ctrl, err = newUnmanagedController(controllerName, blder.mgr, ctrlOptions)
+		return err
+	}
 	blder.ctrl, err = newController(controllerName, blder.mgr, ctrlOptions)
 	return err
 }
@@ -0,0 +1,44 @@
+package builder
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Builder", func() {
+	Describe("ownerLabelsMapFn", func() {
+		gk := schema.GroupKind{Group: "example.com", Kind: "App"}
+
+		It("should map a resource to the owner of its labels", func() {
+			owner := &unstructured.Unstructured{}
+			owner.SetNamespace("default")
+			owner.SetName("owner")
+			u := &unstructured.Unstructured{}
+			u.SetName("a")
+			Expect(meta.AddOwnerLabels(u, owner, gk)).To(Succeed())
+
+			Expect(ownerLabelsMapFn(gk)(u)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "default", Name: "owner"}},
+			}))
+		})
+
+		It("should not map a resource without owner labels", func() {
+			u := &unstructured.Unstructured{}
+			u.SetName("a")
+			Expect(ownerLabelsMapFn(gk)(u)).To(BeEmpty())
+		})
+
+		It("should not map a resource owned by another group kind", func() {
+			owner := &unstructured.Unstructured{}
+			owner.SetName("owner")
+			u := &unstructured.Unstructured{}
+			Expect(meta.AddOwnerLabels(u, owner, schema.GroupKind{Group: "example.com", Kind: "Other"})).To(Succeed())
+			Expect(ownerLabelsMapFn(gk)(u)).To(BeEmpty())
+		})
+	})
+})
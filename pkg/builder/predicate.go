@@ -0,0 +1,170 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/itchyny/gojq"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+var namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+
+// getPredicates returns the global predicates and the predicates of the
+// resource of the for, own or watch
+func (blder *builder) getPredicates(fow ccsyntax.FOWS, gvk *schema.GroupVersionKind) ([]predicate.Predicate, error) {
+	preds := append([]predicate.Predicate(nil), blder.globalPredicates...)
+	p := blder.ceCtx.GetPredicates(fow, gvk)
+	if p == nil {
+		return preds, nil
+	}
+	ps, err := compilePredicates(blder.ctx, p, blder.mgr.GetCache())
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile predicates of %s %s: %w", fow, gvk.String(), err)
+	}
+	return append(preds, ps...), nil
+}
+
+// compilePredicates compiles the declarative predicates of a resource into
+// predicates, the namespaces of the namespace selector are read with the
+// supplied reader
+func compilePredicates(ctx context.Context, p *ctrlcfgv1.Predicates, c client.Reader) ([]predicate.Predicate, error) {
+	l := ctrl.Log.WithName("lcnc predicate")
+
+	preds := []predicate.Predicate{}
+	if len(p.EventTypes) != 0 {
+		preds = append(preds, eventTypePredicate(p.EventTypes))
+	}
+	if p.GenerationChanged {
+		preds = append(preds, predicate.GenerationChangedPredicate{})
+	}
+	if p.LabelSelector != nil {
+		lp, err := predicate.LabelSelectorPredicate(*p.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labelSelector: %w", err)
+		}
+		preds = append(preds, lp)
+	}
+	if p.AnnotationSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(p.AnnotationSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotationSelector: %w", err)
+		}
+		preds = append(preds, predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return sel.Matches(labels.Set(o.GetAnnotations()))
+		}))
+	}
+	if p.NamespaceSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(p.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+		preds = append(preds, namespaceSelectorPredicate(ctx, sel, c, l))
+	}
+	if p.Expression != "" {
+		ep, err := expressionPredicate(p.Expression, l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %s: %w", p.Expression, err)
+		}
+		preds = append(preds, ep)
+	}
+	return preds, nil
+}
+
+// eventTypePredicate passes the events of the supplied types, generic events
+// always pass
+func eventTypePredicate(eventTypes []ctrlcfgv1.EventType) predicate.Predicate {
+	pass := map[ctrlcfgv1.EventType]bool{}
+	for _, et := range eventTypes {
+		pass[et] = true
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return pass[ctrlcfgv1.EventTypeCreate] },
+		UpdateFunc:  func(event.UpdateEvent) bool { return pass[ctrlcfgv1.EventTypeUpdate] },
+		DeleteFunc:  func(event.DeleteEvent) bool { return pass[ctrlcfgv1.EventTypeDelete] },
+		GenericFunc: func(event.GenericEvent) bool { return true },
+	}
+}
+
+// namespaceSelectorPredicate passes the events of the resources in a namespace
+// with labels that match the selector, the events of cluster scoped resources
+// always pass
+func namespaceSelectorPredicate(ctx context.Context, sel labels.Selector, c client.Reader, l logr.Logger) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		if o.GetNamespace() == "" {
+			return true
+		}
+		ns := meta.GetUnstructuredFromGVK(&namespaceGVK)
+		if err := c.Get(ctx, types.NamespacedName{Name: o.GetNamespace()}, ns); err != nil {
+			l.Error(err, "cannot get namespace", "namespace", o.GetNamespace())
+			return false
+		}
+		return sel.Matches(labels.Set(ns.GetLabels()))
+	})
+}
+
+// expressionPredicate passes the events for which the jq expression results
+// in true, the resource before and after the event are available as $old
+// and $new
+func expressionPredicate(exp string, l logr.Logger) (predicate.Predicate, error) {
+	q, err := gojq.Parse(exp)
+	if err != nil {
+		return nil, err
+	}
+	code, err := gojq.Compile(q, gojq.WithVariables([]string{"$old", "$new"}))
+	if err != nil {
+		return nil, err
+	}
+	eval := func(oldObj, newObj client.Object) bool {
+		iter := code.Run(nil, getJQValue(oldObj), getJQValue(newObj))
+		v, ok := iter.Next()
+		if !ok {
+			return false
+		}
+		if err, ok := v.(error); ok {
+			l.Error(err, "cannot evaluate predicate expression", "expression", exp)
+			return false
+		}
+		b, ok := v.(bool)
+		if !ok {
+			l.Info("predicate expression must result in a bool", "expression", exp, "type", fmt.Sprintf("%T", v))
+			return false
+		}
+		return b
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return eval(nil, e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return eval(e.ObjectOld, e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return eval(e.Object, nil) },
+		GenericFunc: func(e event.GenericEvent) bool { return eval(nil, e.Object) },
+	}, nil
+}
+
+// getJQValue returns a copy of the content of the resource, jq changes the
+// numbers of the content in place
+func getJQValue(o client.Object) any {
+	if o == nil {
+		return nil
+	}
+	if u, ok := o.(*unstructured.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent())
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil
+	}
+	return content
+}
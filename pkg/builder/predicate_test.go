@@ -0,0 +1,240 @@
+package builder
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+func newObject(namespace string, generation int64, labels, annotations map[string]string, spec any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	u.SetAPIVersion("v1")
+	u.SetKind("ConfigMap")
+	u.SetNamespace(namespace)
+	u.SetName("test")
+	u.SetGeneration(generation)
+	u.SetLabels(labels)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func newNamespace(name string, labels map[string]string) *unstructured.Unstructured {
+	ns := meta.GetUnstructuredFromGVK(&namespaceGVK)
+	ns.SetName(name)
+	ns.SetLabels(labels)
+	return ns
+}
+
+// passes returns true when the event passes all the predicates
+func passes(preds []predicate.Predicate, e any) bool {
+	for _, p := range preds {
+		var ok bool
+		switch e := e.(type) {
+		case event.CreateEvent:
+			ok = p.Create(e)
+		case event.UpdateEvent:
+			ok = p.Update(e)
+		case event.DeleteEvent:
+			ok = p.Delete(e)
+		case event.GenericEvent:
+			ok = p.Generic(e)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+var _ = Describe("Builder", func() {
+	Describe("compilePredicates", func() {
+		var (
+			c        client.Client
+			obj      *unstructured.Unstructured
+			otherObj *unstructured.Unstructured
+		)
+
+		// compile returns the predicates and expects them to compile
+		compile := func(p *ctrlcfgv1.Predicates) []predicate.Predicate {
+			preds, err := compilePredicates(context.Background(), p, c)
+			Expect(err).NotTo(HaveOccurred())
+			return preds
+		}
+
+		invalidSelector := func() *metav1.LabelSelector {
+			return &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Invalid"}},
+			}
+		}
+
+		BeforeEach(func() {
+			c = fake.NewClientBuilder().WithObjects(
+				newNamespace("default", map[string]string{"env": "prod"}),
+				newNamespace("other", nil),
+			).Build()
+			obj = newObject("default", 1, map[string]string{"app": "test"}, map[string]string{"lcnc.yndd.io/managed": "true"}, map[string]any{"replicas": int64(1)})
+			otherObj = newObject("other", 1, map[string]string{"app": "other"}, nil, map[string]any{"replicas": int64(1)})
+		})
+
+		It("should pass all events without predicates", func() {
+			Expect(passes(compile(&ctrlcfgv1.Predicates{}), event.CreateEvent{Object: obj})).To(BeTrue())
+		})
+
+		It("should filter the event types", func() {
+			preds := compile(&ctrlcfgv1.Predicates{EventTypes: []ctrlcfgv1.EventType{ctrlcfgv1.EventTypeCreate}})
+			Expect(passes(preds, event.CreateEvent{Object: obj})).To(BeTrue())
+			Expect(passes(preds, event.DeleteEvent{Object: obj})).To(BeFalse())
+		})
+
+		It("should pass generic events for the event types", func() {
+			preds := compile(&ctrlcfgv1.Predicates{EventTypes: []ctrlcfgv1.EventType{ctrlcfgv1.EventTypeCreate}})
+			Expect(passes(preds, event.GenericEvent{Object: obj})).To(BeTrue())
+		})
+
+		It("should pass the updates that change the generation", func() {
+			statusObj := obj.DeepCopy()
+			statusObj.Object["status"] = map[string]any{"ready": true}
+			scaledObj := newObject("default", 2, map[string]string{"app": "test"}, nil, map[string]any{"replicas": int64(3)})
+
+			preds := compile(&ctrlcfgv1.Predicates{GenerationChanged: true})
+			Expect(passes(preds, event.UpdateEvent{ObjectOld: obj, ObjectNew: statusObj})).To(BeFalse())
+			Expect(passes(preds, event.UpdateEvent{ObjectOld: obj, ObjectNew: scaledObj})).To(BeTrue())
+		})
+
+		It("should filter the labels", func() {
+			preds := compile(&ctrlcfgv1.Predicates{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}})
+			Expect(passes(preds, event.CreateEvent{Object: obj})).To(BeTrue())
+			Expect(passes(preds, event.CreateEvent{Object: otherObj})).To(BeFalse())
+		})
+
+		It("should filter the annotations", func() {
+			preds := compile(&ctrlcfgv1.Predicates{AnnotationSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"lcnc.yndd.io/managed": "true"}}})
+			Expect(passes(preds, event.CreateEvent{Object: obj})).To(BeTrue())
+			Expect(passes(preds, event.CreateEvent{Object: otherObj})).To(BeFalse())
+		})
+
+		It("should filter the labels of the namespace", func() {
+			preds := compile(&ctrlcfgv1.Predicates{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}})
+			Expect(passes(preds, event.CreateEvent{Object: obj})).To(BeTrue())
+			Expect(passes(preds, event.CreateEvent{Object: otherObj})).To(BeFalse())
+		})
+
+		It("should pass a cluster scoped resource for a namespace selector", func() {
+			preds := compile(&ctrlcfgv1.Predicates{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}})
+			Expect(passes(preds, event.CreateEvent{Object: newObject("", 1, nil, nil, nil)})).To(BeTrue())
+		})
+
+		It("should not pass a resource in a namespace that does not exist", func() {
+			preds := compile(&ctrlcfgv1.Predicates{NamespaceSelector: &metav1.LabelSelector{}})
+			Expect(passes(preds, event.CreateEvent{Object: newObject("missing", 1, nil, nil, nil)})).To(BeFalse())
+		})
+
+		It("should only pass the events that pass all predicates", func() {
+			preds := compile(&ctrlcfgv1.Predicates{
+				EventTypes:    []ctrlcfgv1.EventType{ctrlcfgv1.EventTypeUpdate},
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			})
+			Expect(passes(preds, event.CreateEvent{Object: obj})).To(BeFalse())
+		})
+
+		It("should fail for an invalid label selector", func() {
+			_, err := compilePredicates(context.Background(), &ctrlcfgv1.Predicates{LabelSelector: invalidSelector()}, c)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an invalid annotation selector", func() {
+			_, err := compilePredicates(context.Background(), &ctrlcfgv1.Predicates{AnnotationSelector: invalidSelector()}, c)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an invalid namespace selector", func() {
+			_, err := compilePredicates(context.Background(), &ctrlcfgv1.Predicates{NamespaceSelector: invalidSelector()}, c)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an invalid expression", func() {
+			_, err := compilePredicates(context.Background(), &ctrlcfgv1.Predicates{Expression: "$new |"}, c)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("expressionPredicate", func() {
+		var (
+			obj       *unstructured.Unstructured
+			scaledObj *unstructured.Unstructured
+		)
+
+		// compile returns the predicate of the expression and expects it to
+		// compile
+		compile := func(exp string) predicate.Predicate {
+			p, err := expressionPredicate(exp, logr.Discard())
+			Expect(err).NotTo(HaveOccurred())
+			return p
+		}
+
+		BeforeEach(func() {
+			obj = newObject("default", 1, nil, nil, map[string]any{"replicas": int64(1)})
+			scaledObj = newObject("default", 2, nil, nil, map[string]any{"replicas": int64(3)})
+		})
+
+		It("should evaluate a create without old resource", func() {
+			Expect(compile("$old == null and $new.spec.replicas == 1").Create(event.CreateEvent{Object: obj})).To(BeTrue())
+		})
+
+		It("should evaluate a delete without new resource", func() {
+			Expect(compile("$new == null and $old.spec.replicas == 1").Delete(event.DeleteEvent{Object: obj})).To(BeTrue())
+		})
+
+		It("should evaluate a generic event as a create", func() {
+			Expect(compile("$old == null and $new.spec.replicas == 1").Generic(event.GenericEvent{Object: obj})).To(BeTrue())
+		})
+
+		It("should compare the old and the new resource of an update", func() {
+			p := compile("$old.spec.replicas != $new.spec.replicas")
+			Expect(p.Update(event.UpdateEvent{ObjectOld: obj, ObjectNew: scaledObj})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: obj, ObjectNew: obj})).To(BeFalse())
+		})
+
+		It("should compare numbers", func() {
+			Expect(compile("$new.spec.replicas > 2").Update(event.UpdateEvent{ObjectOld: obj, ObjectNew: scaledObj})).To(BeTrue())
+		})
+
+		It("should not pass a result that is not a bool", func() {
+			Expect(compile("$new.spec").Create(event.CreateEvent{Object: obj})).To(BeFalse())
+		})
+
+		It("should not pass an expression without a result", func() {
+			Expect(compile("empty").Create(event.CreateEvent{Object: obj})).To(BeFalse())
+		})
+
+		It("should not pass an expression that fails", func() {
+			Expect(compile("$new.spec.replicas | error").Create(event.CreateEvent{Object: obj})).To(BeFalse())
+		})
+
+		It("should not change the resource", func() {
+			o := obj.DeepCopy()
+			Expect(compile("$new.spec.replicas > 0").Create(event.CreateEvent{Object: o})).To(BeTrue())
+			Expect(o).To(Equal(obj))
+		})
+
+		It("should fail for a parse error", func() {
+			_, err := expressionPredicate("$new |", logr.Discard())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for an unknown variable", func() {
+			_, err := expressionPredicate("$other.spec", logr.Discard())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package ccsyntax_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCcsyntax(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ccsyntax Suite")
+}
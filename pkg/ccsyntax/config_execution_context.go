@@ -2,6 +2,7 @@ package ccsyntax
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
@@ -15,9 +16,16 @@ type ConfigExecutionContext interface {
 	Add(oc *OriginContext) error
 	AddBlock(oc *OriginContext) error
 	GetDAG(oc *OriginContext) rtdag.RuntimeDAG
+	GetBlockDAG(oc *OriginContext) rtdag.RuntimeDAG
 	GetDAGCtx(fow FOWS, gvk *schema.GroupVersionKind, op Operation) *RTDAGCtx
 	GetFOW(fow FOWS) map[schema.GroupVersionKind]OperationCtx
 	GetForGVK() *schema.GroupVersionKind
+	// AddPredicates adds the predicates of the resource the origin
+	// context refers to
+	AddPredicates(oc *OriginContext, p *ctrlcfgv1.Predicates)
+	// GetPredicates returns the predicates of the resource, nil when the
+	// resource has no predicates
+	GetPredicates(fow FOWS, gvk *schema.GroupVersionKind) *ctrlcfgv1.Predicates
 	AddService(gvk *schema.GroupVersionKind, fn ctrlcfgv1.Function) error
 	GetServices() service.Services
 	Print()
@@ -29,6 +37,7 @@ type cfgExecContext struct {
 	For        map[schema.GroupVersionKind]OperationCtx
 	own        map[schema.GroupVersionKind]OperationCtx
 	watch      map[schema.GroupVersionKind]OperationCtx
+	predicates map[FOWS]map[schema.GroupVersionKind]*ctrlcfgv1.Predicates
 	serviceIdx int
 	services   map[schema.GroupVersionKind]ServiceCtx
 }
@@ -43,8 +52,55 @@ type ServiceCtx struct {
 type RTDAGCtx struct {
 	DAG            rtdag.RuntimeDAG
 	RootVertexName string
+	PipelineName   string
 	m              sync.RWMutex
-	BlockDAGs      map[string]rtdag.RuntimeDAG
+	// BlockDAGs holds the DAGs of the blocks in the pipeline DAG,
+	// the key is the name of the block vertex
+	BlockDAGs map[string]*BlockDAGCtx
+}
+
+// BlockDAGCtx holds the DAG of a function block and the DAGs of the
+// blocks that are nested within the block
+type BlockDAGCtx struct {
+	DAG       rtdag.RuntimeDAG
+	BlockDAGs map[string]*BlockDAGCtx
+}
+
+// getBlockDAGCtx returns the block DAG context at the end of the block path
+func (r *RTDAGCtx) getBlockDAGCtx(blockPath []string) *BlockDAGCtx {
+	blockDAGs := r.BlockDAGs
+	var bctx *BlockDAGCtx
+	for _, blockVertexName := range blockPath {
+		var ok bool
+		bctx, ok = blockDAGs[blockVertexName]
+		if !ok {
+			return nil
+		}
+		blockDAGs = bctx.BlockDAGs
+	}
+	return bctx
+}
+
+// WalkBlockDAGs calls the function for every block DAG in the tree of blocks,
+// the blocks are walked in alphabetical order and a block is walked before
+// the blocks nested within it
+func (r *RTDAGCtx) WalkBlockDAGs(fn func(blockPath []string, d rtdag.RuntimeDAG)) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	walkBlockDAGs(nil, r.BlockDAGs, fn)
+}
+
+func walkBlockDAGs(blockPath []string, blockDAGs map[string]*BlockDAGCtx, fn func(blockPath []string, d rtdag.RuntimeDAG)) {
+	blockVertexNames := make([]string, 0, len(blockDAGs))
+	for blockVertexName := range blockDAGs {
+		blockVertexNames = append(blockVertexNames, blockVertexName)
+	}
+	sort.Strings(blockVertexNames)
+	for _, blockVertexName := range blockVertexNames {
+		p := appendPath(blockPath, blockVertexName)
+		fn(p, blockDAGs[blockVertexName].DAG)
+		walkBlockDAGs(p, blockDAGs[blockVertexName].BlockDAGs, fn)
+	}
 }
 
 func NewConfigExecutionContext(n string) ConfigExecutionContext {
@@ -54,6 +110,7 @@ func NewConfigExecutionContext(n string) ConfigExecutionContext {
 		For:        make(map[schema.GroupVersionKind]OperationCtx),
 		own:        make(map[schema.GroupVersionKind]OperationCtx),
 		watch:      make(map[schema.GroupVersionKind]OperationCtx),
+		predicates: make(map[FOWS]map[schema.GroupVersionKind]*ctrlcfgv1.Predicates),
 		services:   make(map[schema.GroupVersionKind]ServiceCtx),
 	}
 }
@@ -73,12 +130,12 @@ func (r *cfgExecContext) Add(oc *OriginContext) error {
 			OperationApply: {
 				DAG:            rtdag.New(),
 				RootVertexName: oc.VertexName,
-				BlockDAGs:      map[string]rtdag.RuntimeDAG{},
+				BlockDAGs:      map[string]*BlockDAGCtx{},
 			},
 			OperationDelete: {
 				DAG:            rtdag.New(),
 				RootVertexName: oc.VertexName,
-				BlockDAGs:      map[string]rtdag.RuntimeDAG{},
+				BlockDAGs:      map[string]*BlockDAGCtx{},
 			},
 		}
 	case FOWOwn:
@@ -88,7 +145,7 @@ func (r *cfgExecContext) Add(oc *OriginContext) error {
 			OperationApply: {
 				DAG:            rtdag.New(),
 				RootVertexName: oc.VertexName,
-				BlockDAGs:      map[string]rtdag.RuntimeDAG{},
+				BlockDAGs:      map[string]*BlockDAGCtx{},
 			},
 		}
 	default:
@@ -97,6 +154,8 @@ func (r *cfgExecContext) Add(oc *OriginContext) error {
 	return nil
 }
 
+// AddBlock adds the DAG of the block the vertex represents to the tree of
+// block DAGs, the block is nested in the block DAG of the vertex
 func (r *cfgExecContext) AddBlock(oc *OriginContext) error {
 	dctx := r.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation)
 	if dctx == nil {
@@ -104,7 +163,18 @@ func (r *cfgExecContext) AddBlock(oc *OriginContext) error {
 	}
 	dctx.m.Lock()
 	defer dctx.m.Unlock()
-	dctx.BlockDAGs[oc.VertexName] = rtdag.New()
+	blockDAGs := dctx.BlockDAGs
+	if len(oc.BlockPath) != 0 {
+		bctx := dctx.getBlockDAGCtx(oc.BlockPath)
+		if bctx == nil {
+			return fmt.Errorf("block dag context not found, got: %v", oc.BlockPath)
+		}
+		blockDAGs = bctx.BlockDAGs
+	}
+	blockDAGs[oc.VertexName] = &BlockDAGCtx{
+		DAG:       rtdag.New(),
+		BlockDAGs: map[string]*BlockDAGCtx{},
+	}
 	return nil
 }
 
@@ -146,17 +216,36 @@ func (r *cfgExecContext) GetDAGCtx(fow FOWS, gvk *schema.GroupVersionKind, op Op
 	return nil
 }
 
+// GetDAG returns the DAG the vertex is part of, this is the pipeline DAG
+// or the DAG of the block the vertex is nested in
 func (r *cfgExecContext) GetDAG(oc *OriginContext) rtdag.RuntimeDAG {
 	dctx := r.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation)
 	if dctx == nil {
 		return nil
 	}
-	if oc.BlockIndex == 0 && oc.BlockVertexName == "" {
+	if len(oc.BlockPath) == 0 {
 		return dctx.DAG
 	}
+	return r.getBlockDAG(dctx, oc.BlockPath)
+}
+
+// GetBlockDAG returns the DAG of the block the vertex represents
+func (r *cfgExecContext) GetBlockDAG(oc *OriginContext) rtdag.RuntimeDAG {
+	dctx := r.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation)
+	if dctx == nil {
+		return nil
+	}
+	return r.getBlockDAG(dctx, oc.getBlockPath())
+}
+
+func (r *cfgExecContext) getBlockDAG(dctx *RTDAGCtx, blockPath []string) rtdag.RuntimeDAG {
 	dctx.m.RLock()
 	defer dctx.m.RUnlock()
-	return dctx.BlockDAGs[oc.BlockVertexName]
+	bctx := dctx.getBlockDAGCtx(blockPath)
+	if bctx == nil {
+		return nil
+	}
+	return bctx.DAG
 }
 
 func (r *cfgExecContext) GetFOW(fow FOWS) map[schema.GroupVersionKind]OperationCtx {
@@ -197,6 +286,24 @@ func (r *cfgExecContext) GetForGVK() *schema.GroupVersionKind {
 	return &schema.GroupVersionKind{}
 }
 
+func (r *cfgExecContext) AddPredicates(oc *OriginContext, p *ctrlcfgv1.Predicates) {
+	if p == nil {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, ok := r.predicates[oc.FOWS]; !ok {
+		r.predicates[oc.FOWS] = map[schema.GroupVersionKind]*ctrlcfgv1.Predicates{}
+	}
+	r.predicates[oc.FOWS][*oc.GVK] = p
+}
+
+func (r *cfgExecContext) GetPredicates(fow FOWS, gvk *schema.GroupVersionKind) *ctrlcfgv1.Predicates {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.predicates[fow][*gvk]
+}
+
 func (r *cfgExecContext) AddService(gvk *schema.GroupVersionKind, fn ctrlcfgv1.Function) error {
 	if _, ok := r.services[*gvk]; ok {
 		return fmt.Errorf("duplicate gvk service entry: %s", gvk.String())
@@ -226,11 +333,11 @@ func (r *cfgExecContext) Print() {
 		for op, dctx := range oc {
 			fmt.Printf("  op: %s, RootVertexName: %s, blockDAGs: %d\n", op, dctx.RootVertexName, len(dctx.BlockDAGs))
 			dctx.DAG.PrintVertices()
-			for rootVertexName, d := range dctx.BlockDAGs {
-				fmt.Printf("!!!!!!! block dag start: vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
+			dctx.WalkBlockDAGs(func(blockPath []string, d rtdag.RuntimeDAG) {
+				fmt.Printf("!!!!!!! block dag start: blockPath: %v, %s !!!!!!!!!!\n", blockPath, d.GetRootVertex())
 				d.PrintVertices()
-				fmt.Printf("!!!!!!! block dag stop : vertexName: %s, %s !!!!!!!!!!\n", rootVertexName, d.GetRootVertex())
-			}
+				fmt.Printf("!!!!!!! block dag stop : blockPath: %v, %s !!!!!!!!!!\n", blockPath, d.GetRootVertex())
+			})
 		}
 	}
 }
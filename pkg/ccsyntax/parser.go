@@ -1,8 +1,12 @@
 package ccsyntax
 
 import (
+	"fmt"
+
 	"github.com/go-logr/logr"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/dag"
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -59,6 +63,12 @@ func (r *parser) Parse() (ConfigExecutionContext, []Result) {
 		r.l.Info("connect failed")
 		return nil, result
 	}
+	// a cyclic graph can never be executed
+	result = r.detectCycles(ceCtx)
+	if len(result) != 0 {
+		r.l.Info("cycle detection failed")
+		return nil, result
+	}
 	// optimizes the dependncy graph based on transit reduction
 	// techniques
 	r.transitivereduction(ceCtx)
@@ -67,14 +77,55 @@ func (r *parser) Parse() (ConfigExecutionContext, []Result) {
 	return ceCtx, nil
 }
 
+func (r *parser) detectCycles(ceCtx ConfigExecutionContext) []Result {
+	result := []Result{}
+	for _, fow := range []FOWS{FOWFor, FOWWatch} {
+		for gvk, od := range ceCtx.GetFOW(fow) {
+			gvk := gvk
+			for op, dctx := range od {
+				oc := &OriginContext{
+					FOWS:           fow,
+					RootVertexName: dctx.RootVertexName,
+					GVK:            &gvk,
+					Operation:      op,
+					Pipeline:       dctx.PipelineName,
+					PipelineIndex:  r.getPipelineIndex(dctx.PipelineName),
+					Origin:         OriginFunction,
+				}
+				if cycle := dctx.DAG.GetCycle(); len(cycle) != 0 {
+					result = append(result, Result{
+						OriginContext: oc,
+						Error:         fmt.Errorf("cycle detected: %s", dag.CycleString(cycle)).Error(),
+					})
+				}
+				dctx.WalkBlockDAGs(func(blockPath []string, d rtdag.RuntimeDAG) {
+					if cycle := d.GetCycle(); len(cycle) != 0 {
+						blockVertexName := blockPath[len(blockPath)-1]
+						oc := oc.DeepCopy()
+						oc.Block = true
+						oc.BlockIndex = len(blockPath)
+						oc.BlockVertexName = blockVertexName
+						oc.BlockPath = blockPath
+						result = append(result, Result{
+							OriginContext: oc,
+							Error:         fmt.Errorf("cycle detected in block %s: %s", blockVertexName, dag.CycleString(cycle)).Error(),
+						})
+					}
+				})
+			}
+		}
+	}
+	return result
+}
+
 func (r *parser) transitivereduction(ceCtx ConfigExecutionContext) {
 	// transitive reduction for For dag
 	for _, od := range ceCtx.GetFOW(FOWFor) {
 		for _, dctx := range od {
 			dctx.DAG.TransitiveReduction()
-			for _, d := range dctx.BlockDAGs {
+			dctx.WalkBlockDAGs(func(_ []string, d rtdag.RuntimeDAG) {
 				d.TransitiveReduction()
-			}
+			})
 		}
 
 	}
@@ -82,9 +133,9 @@ func (r *parser) transitivereduction(ceCtx ConfigExecutionContext) {
 	for _, od := range ceCtx.GetFOW(FOWWatch) {
 		for _, dctx := range od {
 			dctx.DAG.TransitiveReduction()
-			for _, d := range dctx.BlockDAGs {
+			dctx.WalkBlockDAGs(func(_ []string, d rtdag.RuntimeDAG) {
 				d.TransitiveReduction()
-			}
+			})
 		}
 	}
 }
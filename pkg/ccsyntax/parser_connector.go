@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax/vardag"
 	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -85,11 +86,12 @@ func (r *connector) connectFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 		for _, v := range v.Input.GenericInput {
 			r.connectRefs(oc, v)
 		}
-		if v.Input.Selector != nil {
-			for k, v := range v.Input.Selector.MatchLabels {
-				r.connectRefs(oc, k)
-				r.connectRefs(oc, v)
-			}
+		if t := getTemplate(v); t != "" {
+			r.connectTemplateRefs(oc, t)
+		}
+		// the query options refer to other variables through jq expressions
+		for _, exp := range v.Input.GetQueryExpressions() {
+			r.connectRefs(oc, exp)
 		}
 	}
 
@@ -124,15 +126,29 @@ func (r *connector) connectBlock(oc *OriginContext, v ctrlcfgv1.Block) {
 }
 
 func (r *connector) connectRefs(oc *OriginContext, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs, err := NewReferences().GetReferences(s)
+	if err != nil {
+		// syntax errors are reported by the syntax validator
+		return
+	}
+	r.connectReferences(oc, refs)
+}
+
+func (r *connector) connectTemplateRefs(oc *OriginContext, s string) {
+	refs, err := NewReferences().GetTemplateReferences(s)
+	if err != nil {
+		// syntax errors are reported by the syntax validator
+		return
+	}
+	r.connectReferences(oc, refs)
+}
 
+func (r *connector) connectReferences(oc *OriginContext, refs []*Reference) {
 	for _, ref := range refs {
 		// RangeRefKind do nothing
 		// for regular values we resolve the variables
-		// for variables that start with _ this is a special case and
-		// should only be used within a jq construct
-		if ref.Kind == RegularReferenceKind && ref.Value[0] != '_' {
+		// variables bound within the expression are not returned as references
+		if ref.Kind == RegularReferenceKind {
 			// get the vertexContext from the function
 			fmt.Printf("oc: %#v, ref: %#v, gvk: %s\n", oc, ref, oc.GVK.String())
 			d := r.ceCtx.GetDAG(oc)
@@ -171,28 +187,41 @@ func (r *connector) connectRefs(oc *OriginContext, s string) {
 				continue
 			}
 
-			switch {
-			case varInfo.BlockIndex < oc.BlockIndex:
-				//the reference points to the root DAG, so we need to wire it to the root of the block instead
-				fmt.Printf("connect: %s -> %s, oc: %#v, ref: %#v\n", oc.VertexName, varInfo.VertexName, oc, ref)
-				d.Connect(oc.BlockVertexName, oc.VertexName)
-				// connect the block vertex to the original vertex in the root DAG
-				oc := oc.DeepCopy()
-				oc.BlockIndex = 0
-				oc.BlockVertexName = ""
-				rootDAG := r.ceCtx.GetDAG(oc)
-				rootDAG.Connect(varInfo.VertexName, oc.BlockVertexName)
-			case varInfo.BlockIndex > oc.BlockIndex:
-				// the reference points to an element in the block DAG, so we point to the root of the block
-				d.Connect(varInfo.BlockVertexName, oc.VertexName)
-			case varInfo.BlockIndex == oc.BlockIndex:
-				d.Connect(varInfo.OutputVertex, oc.VertexName)
+			// the edge is added in the innermost DAG both vertices are nested in
+			// e.g. a reference to the pipeline DAG from within a block is connected
+			// to the outermost block the referencing vertex is nested in
+			blockPath, from, to := getScopedEdge(oc, varInfo)
+			if from == to {
+				// reported by the resolver
+				continue
 			}
-
+			soc := oc.DeepCopy()
+			soc.BlockPath = blockPath
+			r.ceCtx.GetDAG(soc).Connect(from, to)
 		}
 	}
 }
 
+// getScopedEdge returns the path of the innermost block DAG the vertex producing
+// the variable and the vertex referencing it are nested in, together with the
+// vertices that need to be connected in that DAG. These are the vertices
+// themselves or the blocks that contain them at that level.
+func getScopedEdge(oc *OriginContext, varInfo *vardag.VariableContext) ([]string, string, string) {
+	n := 0
+	for n < len(oc.BlockPath) && n < len(varInfo.BlockPath) && oc.BlockPath[n] == varInfo.BlockPath[n] {
+		n++
+	}
+	from := varInfo.OutputVertex
+	if len(varInfo.BlockPath) > n {
+		from = varInfo.BlockPath[n]
+	}
+	to := oc.VertexName
+	if len(oc.BlockPath) > n {
+		to = oc.BlockPath[n]
+	}
+	return oc.BlockPath[:n], from, to
+}
+
 func (r *connector) connectVertex(oc *OriginContext, vertexName string) {
 	d := r.ceCtx.GetDAG(oc)
 	d.Connect(vertexName, oc.VertexName)
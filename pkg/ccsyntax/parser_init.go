@@ -15,8 +15,9 @@ func (r *parser) init() (ConfigExecutionContext, GlobalVariable, []Result) {
 	}
 
 	fnc := &WalkConfig{
-		gvkObjectFn:     i.initGvk,
-		functionBlockFn: i.initFunctionBlock,
+		gvkObjectFn:       i.initGvk,
+		pipelinePreHookFn: i.initPipeline,
+		functionBlockFn:   i.initFunctionBlock,
 	}
 	// walk the config initialaizes the config execution context
 	r.walkLcncConfig(fnc)
@@ -47,29 +48,31 @@ func (r *initializer) initGvk(oc *OriginContext, v *ctrlcfgv1.GvkObject) *schema
 		})
 	}
 	oc.GVK = gvk
-	// initialize execution context for thr for and watch
-	if oc.FOWS == FOWFor || oc.FOWS == FOWWatch {
-		// initialize the gvk and rootVertex in the execution context
-		if err := r.cec.Add(oc); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
+	// initialize the gvk and rootVertex in the execution context, an own
+	// resource has no pipelines
+	if err := r.cec.Add(oc); err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
 	}
+	r.cec.AddPredicates(oc, v.Predicates)
 	// initialize the output context
 	r.gvar.Add(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.VertexName})
 	return gvk
 }
 
-func (r *initializer) initFunctionBlock(oc *OriginContext, v *ctrlcfgv1.FunctionElement) {
-	if oc.BlockIndex >= 1 {
-		// we can only have 1 block index -> only 1 recursion allowed
-		r.recordResult(Result{
-			OriginContext: oc,
-			Error:         fmt.Errorf("a pipeline van only have 1function block %v", *oc).Error(),
-		})
+func (r *initializer) initPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
+	if oc.GVK == nil {
+		return
 	}
+	// own resources and the delete operation of a watch have no dag context
+	if dctx := r.cec.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation); dctx != nil {
+		dctx.PipelineName = v.Name
+	}
+}
+
+func (r *initializer) initFunctionBlock(oc *OriginContext, v *ctrlcfgv1.FunctionElement) {
 	if !v.Function.HasBlock() {
 		r.recordResult(Result{
 			OriginContext: oc,
@@ -69,6 +69,7 @@ func (r *populator) addGvk(oc *OriginContext, v *ctrlcfgv1.GvkObject) *schema.Gr
 		OutputVertex:    oc.VertexName,
 		BlockIndex:      oc.BlockIndex,
 		BlockVertexName: oc.BlockVertexName,
+		BlockPath:       oc.BlockPath,
 	}); err != nil {
 		r.recordResult(Result{
 			OriginContext: oc,
@@ -134,9 +135,10 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 			})
 		}
 		outputs.AddEntry(varName, &output.OutputInfo{
-			Internal:    outputCfg.Internal,
-			Conditioned: outputCfg.Conditioned,
-			GVK:         gvk,
+			Internal:       outputCfg.Internal,
+			Conditioned:    outputCfg.Conditioned,
+			GVK:            gvk,
+			ForceConflicts: outputCfg.ForceConflicts,
 		})
 		gvkToVarName[meta.GVKToString(gvk)] = varName
 
@@ -146,6 +148,7 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 			OutputVertex:    oc.VertexName,
 			BlockIndex:      oc.BlockIndex,
 			BlockVertexName: oc.BlockVertexName,
+			BlockPath:       oc.BlockPath,
 		}); err != nil {
 			r.recordResult(Result{
 				OriginContext: oc,
@@ -198,6 +201,7 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 			OutputVertex:    oc.VertexName,
 			BlockIndex:      oc.BlockIndex,
 			BlockVertexName: oc.BlockVertexName,
+			BlockPath:       oc.BlockPath,
 		}); err != nil {
 			r.recordResult(Result{
 				OriginContext: oc,
@@ -206,84 +210,46 @@ func (r *populator) addFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 		}
 	}
 
-	// add the function vertex to the dag
-	// if there is a functionblock we could have a different DAG -> select the right dag
-	// BlockDAG = nil -> no range -> process regularly and add everything to the main runtimeDAG
-	// BlockDAG NOT nil
-	// + oc.BlockIndex == 0 this is the initial block index and we need to add the vertex to both the main runtimeDAG
-	//   and the block runtime DAG -> in the main runtimeDAG add the blockDAG
-	// + oc.BlockIndex !=0 (1) this is a block DAG, so process regurlary in the block DAG
-	if !oc.Block {
-		// this is a regular entry in the main runtime DAG
-		if err := r.cec.GetDAG(oc).AddVertex(oc.VertexName, &rtdag.VertexContext{
-			VertexName:   oc.VertexName,
-			Kind:         rtdag.FunctionVertexKind,
-			Function:     *v,
-			References:   []string{},   // initialize reference
-			Outputs:      outputs,      // provide the preparsed output context to the vertex
-			GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
-		}); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
-		r.cec.GetDAG(oc).PrintVertices()
-		return
+	// add the function vertex to the dag the vertex is part of, this is the pipeline DAG
+	// or the DAG of the block the vertex is nested in
+	// A block vertex is a regular vertex in its DAG and refers to its own block DAG, which
+	// has the block vertex as root vertex. The functions in the block are added to the block DAG
+	// and can be blocks themselves.
+	vc := &rtdag.VertexContext{
+		VertexName:   oc.VertexName,
+		Kind:         rtdag.FunctionVertexKind,
+		Function:     *v,
+		References:   []string{},   // initialize reference
+		Outputs:      outputs,      // provide the preparsed output context to the vertex
+		GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
 	}
-	// This is a block
-	rootDAG := r.cec.GetDAG(oc)
-	if oc.BlockVertexName == "" {
-		oc.BlockVertexName = oc.VertexName
+	if v.Type == ctrlcfgv1.BlockType {
+		vc.BlockDAG = r.cec.GetBlockDAG(oc)
 	}
-	blockDAG := r.cec.GetDAG(oc)
-	if oc.BlockIndex == 0 {
-		// this is the initial block index and we need to add the vertex to both the main runtimeDAG
-		// and the block runtime DAG -> in the main runtimeDAG add the blockDAG
-		if err := rootDAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
-			VertexName:   oc.VertexName,
-			Kind:         rtdag.FunctionVertexKind,
-			BlockDAG:     blockDAG,
-			Function:     *v,
-			References:   []string{},   // initialize reference
-			Outputs:      outputs,      // provide the preparsed output context to the vertex
-			GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
-		}); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
-		// add the vertex to the blockDAG
-		if err := blockDAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
-			VertexName: oc.VertexName,
-			Kind:       rtdag.RootVertexKind, // this is the rootVertex in the blockDAG
-			Function: ctrlcfgv1.Function{
-				Type: ctrlcfgv1.RootType,
-			},
-			References:   []string{},   // initialize reference
-			Outputs:      outputs,      // provide the preparsed output context to the vertex
-			GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
-		}); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
-	} else {
-		if err := blockDAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
-			VertexName:   oc.VertexName,
-			Kind:         rtdag.FunctionVertexKind,
-			Function:     *v,
-			References:   []string{},   // initialize reference
-			Outputs:      outputs,      // provide the preparsed output context to the vertex
-			GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
-		}); err != nil {
-			r.recordResult(Result{
-				OriginContext: oc,
-				Error:         err.Error(),
-			})
-		}
+	if err := r.cec.GetDAG(oc).AddVertex(oc.VertexName, vc); err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+	}
+	if vc.BlockDAG == nil {
+		return
+	}
+	// add the root vertex to the blockDAG
+	if err := vc.BlockDAG.AddVertex(oc.VertexName, &rtdag.VertexContext{
+		VertexName: oc.VertexName,
+		Kind:       rtdag.RootVertexKind, // this is the rootVertex in the blockDAG
+		Function: ctrlcfgv1.Function{
+			Type: ctrlcfgv1.RootType,
+		},
+		References:   []string{},   // initialize reference
+		Outputs:      outputs,      // provide the preparsed output context to the vertex
+		GVKToVarName: gvkToVarName, // provide a preparsed mapping from gvk to varName
+	}); err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func (r *parser) resolve(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Result {
@@ -15,8 +16,8 @@ func (r *parser) resolve(ceCtx ConfigExecutionContext, gvar GlobalVariable) []Re
 	}
 
 	fnc := &WalkConfig{
-		//gvkObjectFn: rs.resolveGvk,
-		functionFn: rs.resolveFunction,
+		gvkObjectFn: rs.resolveGvk,
+		functionFn:  rs.resolveFunction,
 	}
 
 	// walk the config resolve the verteces and create the outputmapping
@@ -38,34 +39,49 @@ func (r *resolver) recordResult(result Result) {
 	r.result = append(r.result, result)
 }
 
+func (r *resolver) resolveGvk(oc *OriginContext, v *ctrlcfgv1.GvkObject) *schema.GroupVersionKind {
+	gvk, err := ctrlcfgv1.GetGVK(v.Resource)
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+	}
+	return gvk
+}
+
 func (r *resolver) resolveFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 	for localVarName, v := range v.Vars {
 		oc.LocalVarName = localVarName
 		r.resolveRefs(oc, v)
 	}
+	oc.LocalVarName = ""
 
 	if v.HasBlock() {
 		r.resolveBlock(oc, v.Block)
 	}
 
-	if v.Input.Selector != nil {
-		for k, v := range v.Input.Selector.MatchLabels {
-			r.resolveRefs(oc, k)
-			r.resolveRefs(oc, v)
+	if v.Input != nil {
+		// the query options refer to other variables through jq expressions
+		for _, exp := range v.Input.GetQueryExpressions() {
+			r.resolveRefs(oc, exp)
 		}
-	}
 
-	if v.Input.Key != "" {
-		r.resolveRefs(oc, v.Input.Key)
-	}
-	if v.Input.Value != "" {
-		r.resolveRefs(oc, v.Input.Value)
-	}
-	if v.Input.Expression != "" {
-		r.resolveRefs(oc, v.Input.Expression)
-	}
-	for _, v := range v.Input.GenericInput {
-		r.resolveRefs(oc, v)
+		if v.Input.Key != "" {
+			r.resolveRefs(oc, v.Input.Key)
+		}
+		if v.Input.Value != "" {
+			r.resolveRefs(oc, v.Input.Value)
+		}
+		if v.Input.Expression != "" {
+			r.resolveRefs(oc, v.Input.Expression)
+		}
+		for _, v := range v.Input.GenericInput {
+			r.resolveRefs(oc, v)
+		}
+		if t := getTemplate(v); t != "" {
+			r.resolveTemplateRefs(oc, t)
+		}
 	}
 	if len(v.DependsOn) > 0 {
 		r.resolveDependsOn(oc, v.DependsOn)
@@ -92,14 +108,28 @@ func (r *resolver) resolveBlock(oc *OriginContext, v ctrlcfgv1.Block) {
 }
 
 func (r *resolver) resolveRefs(oc *OriginContext, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs, err := NewReferences().GetReferences(s)
+	if err != nil {
+		// syntax errors are reported by the syntax validator
+		return
+	}
+	r.resolveReferences(oc, refs)
+}
+
+func (r *resolver) resolveTemplateRefs(oc *OriginContext, s string) {
+	refs, err := NewReferences().GetTemplateReferences(s)
+	if err != nil {
+		// syntax errors are reported by the syntax validator
+		return
+	}
+	r.resolveReferences(oc, refs)
+}
 
+func (r *resolver) resolveReferences(oc *OriginContext, refs []*Reference) {
 	for _, ref := range refs {
 		// for regular values we resolve the variables
-		// for variables that start with _ this is a special case and
-		// should only be used within a jq construct
-		if ref.Kind == RegularReferenceKind && ref.Value[0] != '_' {
+		// variables bound within the expression are not returned as references
+		if ref.Kind == RegularReferenceKind {
 			//d := r.ceCtx.GetDAG(oc)
 			// get the vertexContext from the function
 			//vc := d.GetVertex(oc.VertexName)
@@ -111,11 +141,21 @@ func (r *resolver) resolveRefs(oc *OriginContext, s string) {
 				}
 			}
 			// we lookup in the outputDAG
-			if !r.gvar.GetDAG(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.RootVertexName}).VarExists(ref.Value) {
+			varInfo := r.gvar.GetDAG(FOWEntry{FOW: oc.FOWS, RootVertexName: oc.RootVertexName}).GetVarInfo(ref.Value)
+			if varInfo == nil {
 				r.recordResult(Result{
 					OriginContext: oc,
 					Error:         fmt.Errorf("cannot resolve %s", ref.Value).Error(),
 				})
+				continue
+			}
+			// a vertex cannot depend on a variable it produces itself, this includes
+			// the variables produced within the block of a block vertex
+			if _, from, to := getScopedEdge(oc, varInfo); from == to {
+				r.recordResult(Result{
+					OriginContext: oc,
+					Error:         fmt.Errorf("cannot reference %s from %s, the variable is produced within %s", ref.Value, oc.VertexName, to).Error(),
+				})
 			}
 		}
 	}
@@ -123,7 +163,8 @@ func (r *resolver) resolveRefs(oc *OriginContext, s string) {
 
 func (r *resolver) resolveDependsOn(oc *OriginContext, vertexNames []string) {
 	for _, vertexName := range vertexNames {
-		if r.ceCtx.GetDAGCtx(oc.FOWS, oc.GVK, oc.Operation).DAG.GetVertex(vertexName) == nil {
+		// dependencies are resolved within the DAG the vertex is part of
+		if r.ceCtx.GetDAG(oc).GetVertex(vertexName) == nil {
 			r.recordResult(Result{
 				OriginContext: oc,
 				Error:         fmt.Errorf("vertex in depndsOn does not exist %s", vertexName).Error(),
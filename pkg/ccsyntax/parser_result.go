@@ -2,6 +2,7 @@ package ccsyntax
 
 import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type Result struct {
@@ -11,6 +12,48 @@ type Result struct {
 
 type recordResultFn func(Result)
 
+// FieldPath returns the path of the ControllerConfig field the result
+// originates from
+func (r Result) FieldPath() *field.Path {
+	fp := field.NewPath("spec", "properties")
+	oc := r.OriginContext
+	if oc == nil {
+		return fp
+	}
+	switch oc.Origin {
+	case OriginFow, "":
+		if oc.FOWS == "" {
+			return fp
+		}
+		fp = fp.Child(string(oc.FOWS))
+		if oc.VertexName != "" {
+			fp = fp.Child(oc.VertexName)
+		}
+	case OriginService:
+		return fp.Child("services", oc.VertexName)
+	case OriginVariable, OriginFunction:
+		if oc.Pipeline == "" {
+			return fp
+		}
+		fp = fp.Child("pipelines").Index(oc.PipelineIndex)
+		if oc.Origin == OriginVariable {
+			fp = fp.Child("vars")
+		} else {
+			fp = fp.Child("tasks")
+		}
+		for _, blockVertexName := range oc.BlockPath {
+			fp = fp.Child(blockVertexName, "block")
+		}
+		if oc.VertexName != "" {
+			fp = fp.Child(oc.VertexName)
+		}
+		if oc.LocalVarName != "" {
+			fp = fp.Child("vars", oc.LocalVarName)
+		}
+	}
+	return fp
+}
+
 type OriginContext struct {
 	//Index      int
 	FOWS            FOWS                     `json:"fow,omitempty" yaml:"fow,omitempty"`
@@ -18,10 +61,12 @@ type OriginContext struct {
 	GVK             *schema.GroupVersionKind `json:"gvk,omitempty" yaml:"gvk,omitempty"`
 	Operation       Operation                `json:"operation,omitempty" yaml:"operation,omitempty"`
 	Pipeline        string                   `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	PipelineIndex   int                      `json:"pipelineIdx,omitempty" yaml:"pipelineIdx,omitempty"`
 	Origin          Origin                   `json:"origin,omitempty" yaml:"origin,omitempty"`
 	Block           bool                     `json:"block,omitempty" yaml:"block,omitempty"`
 	BlockIndex      int                      `json:"blockIdx,omitempty" yaml:"blockIdx,omitempty"`
 	BlockVertexName string                   `json:"blockVertexName,omitempty" yaml:"blockVertexName,omitempty"`
+	BlockPath       []string                 `json:"blockPath,omitempty" yaml:"blockPath,omitempty"` // block vertices the vertex is nested in, outermost first
 	VertexName      string                   `json:"vertexname,omitempty" yaml:"vertexname,omitempty"`
 	LocalVarName    string                   `json:"localvarName,omitempty" yaml:"localvarName,omitempty"`
 	LocalVars       map[string]string        `json:"localVars,omitempty" yaml:"localvarName,omitempty"`
@@ -38,6 +83,23 @@ func (in *OriginContext) DeepCopy() *OriginContext {
 
 func (in *OriginContext) DeepCopyInto(out *OriginContext) {
 	*out = *in
+	if in.BlockPath != nil {
+		out.BlockPath = make([]string, len(in.BlockPath))
+		copy(out.BlockPath, in.BlockPath)
+	}
+}
+
+// getBlockPath returns the path of the block the vertex represents
+func (in *OriginContext) getBlockPath() []string {
+	return appendPath(in.BlockPath, in.VertexName)
+}
+
+// appendPath returns a new path, such that the path of the parent is
+// not modified
+func appendPath(path []string, vertexName string) []string {
+	p := make([]string, 0, len(path)+1)
+	p = append(p, path...)
+	return append(p, vertexName)
 }
 
 type FOWS string
@@ -0,0 +1,79 @@
+package ccsyntax_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"sigs.k8s.io/yaml"
+)
+
+const invalidPipelines = `
+apiVersion: lcnc.yndd.io/v1
+kind: ControllerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  properties:
+    for:
+      topo:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Definition
+        applyPipelineRef: apply
+        deletePipelineRef: delete
+    pipelines:
+    - name: delete
+    - name: apply
+      vars:
+        badVar:
+          type: map
+          input:
+            value: $topo
+      tasks:
+        badTask:
+          type: slice
+          input: {}
+        blockTask:
+          type: block
+          condition:
+            expression: $topo
+          block:
+            innerTask:
+              type: slice
+              input: {}
+`
+
+var _ = Describe("Result", func() {
+	Describe("FieldPath", func() {
+		var fieldPaths map[string]string
+
+		BeforeEach(func() {
+			cfg := &ctrlcfgv1.ControllerConfig{}
+			Expect(yaml.Unmarshal([]byte(invalidPipelines), cfg)).To(Succeed())
+
+			_, result := ccsyntax.NewParser(cfg)
+			fieldPaths = map[string]string{}
+			for _, res := range result {
+				fieldPaths[res.OriginContext.VertexName] = res.FieldPath().String()
+			}
+		})
+
+		It("should render the path of a variable with the index of the pipeline", func() {
+			Expect(fieldPaths).To(HaveKeyWithValue("badVar", "spec.properties.pipelines[1].vars.badVar"))
+		})
+
+		It("should render the path of a task with the index of the pipeline", func() {
+			Expect(fieldPaths).To(HaveKeyWithValue("badTask", "spec.properties.pipelines[1].tasks.badTask"))
+		})
+
+		It("should render the path of a task in a block", func() {
+			Expect(fieldPaths).To(HaveKeyWithValue("innerTask", "spec.properties.pipelines[1].tasks.blockTask.block.innerTask"))
+		})
+
+		It("should render the properties when the origin is unknown", func() {
+			Expect(ccsyntax.Result{}.FieldPath().String()).To(Equal("spec.properties"))
+		})
+	})
+})
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/itchyny/gojq"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -47,6 +49,12 @@ func (r *vs) validatePreHook(lcncCfg *ctrlcfgv1.ControllerConfig) {
 			Error:         fmt.Errorf("lcnc config must have just 1 for statement, got: %v", lcncCfg.Spec.Properties.For).Error(),
 		})
 	}
+	if lcncCfg.Spec.PollInterval != nil && lcncCfg.Spec.PollInterval.Duration < 0 {
+		r.recordResult(Result{
+			OriginContext: &OriginContext{},
+			Error:         fmt.Errorf("pollInterval cannot be negative, got: %s", lcncCfg.Spec.PollInterval.Duration).Error(),
+		})
+	}
 }
 
 func (r *vs) validateGvk(oc *OriginContext, v *ctrlcfgv1.GvkObject) *schema.GroupVersionKind {
@@ -63,9 +71,54 @@ func (r *vs) validateGvk(oc *OriginContext, v *ctrlcfgv1.GvkObject) *schema.Grou
 			Error:         err.Error(),
 		})
 	}
+	if v.Predicates != nil {
+		r.validatePredicates(oc, v.Predicates)
+	}
 	return gvk
 }
 
+// validatePredicates validates the selectors, event types and expression of
+// the predicates
+func (r *vs) validatePredicates(oc *OriginContext, v *ctrlcfgv1.Predicates) {
+	for name, ls := range map[string]*metav1.LabelSelector{
+		"labelSelector":      v.LabelSelector,
+		"annotationSelector": v.AnnotationSelector,
+		"namespaceSelector":  v.NamespaceSelector,
+	} {
+		if ls == nil {
+			continue
+		}
+		if _, err := metav1.LabelSelectorAsSelector(ls); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid %s: %s", name, err.Error()).Error(),
+			})
+		}
+	}
+	for _, et := range v.EventTypes {
+		switch et {
+		case ctrlcfgv1.EventTypeCreate, ctrlcfgv1.EventTypeUpdate, ctrlcfgv1.EventTypeDelete:
+		default:
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("unsupported event type: %s", et).Error(),
+			})
+		}
+	}
+	if v.Expression != "" {
+		q, err := gojq.Parse(v.Expression)
+		if err == nil {
+			_, err = gojq.Compile(q, gojq.WithVariables([]string{"$old", "$new"}))
+		}
+		if err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid predicate expression %s: %s", v.Expression, err.Error()).Error(),
+			})
+		}
+	}
+}
+
 func (r *vs) validateEmptyPipeline(oc *OriginContext, v *ctrlcfgv1.GvkObject) {
 	issue := false
 	switch oc.FOWS {
@@ -100,13 +153,6 @@ func (r *vs) validateEmptyFunctionElement(oc *OriginContext) {
 // if there is a block
 // if the block has the right symentics
 func (r *vs) validateFunctionBlock(oc *OriginContext, v *ctrlcfgv1.FunctionElement) {
-	if oc.BlockIndex >= 1 {
-		// we can only have 1 block index -> only 1 recursion allowed
-		r.recordResult(Result{
-			OriginContext: oc,
-			Error:         fmt.Errorf("a pipeline van only have 1function block %v", *oc).Error(),
-		})
-	}
 	if !v.Function.HasBlock() {
 		r.recordResult(Result{
 			OriginContext: oc,
@@ -214,9 +260,16 @@ func (r *vs) validateFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 		if v.Input.Value != "" {
 			r.validateContext(oc, v, v.Input.Value)
 		}
+		if v.Input.Expression != "" {
+			r.validateContext(oc, v, v.Input.Expression)
+		}
 		for _, val := range v.Input.GenericInput {
 			r.validateContext(oc, v, val)
 		}
+		if t := getTemplate(v); t != "" {
+			r.validateTemplateContext(oc, v, t)
+		}
+		r.validateQuery(oc, v)
 	}
 
 	// validate Ouput
@@ -240,10 +293,122 @@ func (r *vs) validateFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 		}
 	}
 
-	// validate local vars -> TBD
+	// validate the timeout, retries and backoff
+	r.validateRetryPolicy(oc, v)
+
+	// validate local vars, they are resolved per item of the range so they
+	// can refer to the range variables
+	for _, expression := range v.Vars {
+		r.validateContext(oc, v, expression)
+	}
 
 }
 
+// validateQuery validates the namespace, the label and field selectors, the
+// get and the owner of the input, which are only supported by a query
+func (r *vs) validateQuery(oc *OriginContext, v *ctrlcfgv1.Function) {
+	if v.Input.Selector == nil && v.Input.FieldSelector == nil && v.Input.Namespace == "" &&
+		v.Input.Get == nil && v.Input.OwnedBy == "" {
+		return
+	}
+	if v.Type != ctrlcfgv1.QueryType {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("selector, fieldSelector, namespace, get and ownedBy are only supported in %s, got: %s", ctrlcfgv1.QueryType, v.Type).Error(),
+		})
+		return
+	}
+	if v.Input.Get != nil {
+		if v.Input.Get.Name == "" {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("get needs a name").Error(),
+			})
+		}
+		if v.Input.Selector != nil || v.Input.FieldSelector != nil || v.Input.OwnedBy != "" {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("get cannot be combined with selector, fieldSelector or ownedBy").Error(),
+			})
+		}
+	}
+	if v.Input.OwnedBy != "" && !ctrlcfgv1.IsExpression(v.Input.OwnedBy) {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("ownedBy must be a jq expression resulting in the owner e.g. the for object, got: %s", v.Input.OwnedBy).Error(),
+		})
+	}
+	for _, exp := range v.Input.GetQueryExpressions() {
+		r.validateContext(oc, v, exp)
+	}
+	if v.Input.Selector != nil {
+		// the expressions are replaced by a valid value to validate the selector syntax
+		ls := v.Input.Selector.DeepCopy()
+		for k, val := range ls.MatchLabels {
+			if ctrlcfgv1.IsExpression(val) {
+				ls.MatchLabels[k] = "x"
+			}
+		}
+		if _, err := metav1.LabelSelectorAsSelector(ls); err != nil {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("invalid selector: %s", err.Error()).Error(),
+			})
+		}
+	}
+}
+
+// validateRetryPolicy validates the timeout, retries and backoff of a function
+func (r *vs) validateRetryPolicy(oc *OriginContext, v *ctrlcfgv1.Function) {
+	if v.Timeout != nil && v.Timeout.Duration <= 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("timeout must be positive, got: %s", v.Timeout.Duration).Error(),
+		})
+	}
+	if v.Retries < 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("retries cannot be negative, got: %d", v.Retries).Error(),
+		})
+	}
+	if v.Backoff == nil {
+		return
+	}
+	if v.Retries == 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("backoff is only relevant with retries").Error(),
+		})
+	}
+	if v.Backoff.Duration != nil && v.Backoff.Duration.Duration <= 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("backoff duration must be positive, got: %s", v.Backoff.Duration.Duration).Error(),
+		})
+	}
+	if v.Backoff.Factor < 0 {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         fmt.Errorf("backoff factor cannot be negative, got: %d", v.Backoff.Factor).Error(),
+		})
+	}
+	if v.Backoff.Max != nil {
+		if v.Backoff.Max.Duration <= 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("backoff max must be positive, got: %s", v.Backoff.Max.Duration).Error(),
+			})
+		}
+		if v.Backoff.Duration != nil && v.Backoff.Max.Duration < v.Backoff.Duration.Duration {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("backoff max %s cannot be smaller than the duration %s", v.Backoff.Max.Duration, v.Backoff.Duration.Duration).Error(),
+			})
+		}
+	}
+}
+
 // valdates the service function
 func (r *vs) validateServiceFunction(oc *OriginContext, v *ctrlcfgv1.Function) {
 	// validate Ouput
@@ -293,22 +458,39 @@ func (r *vs) validateBlock(oc *OriginContext, v ctrlcfgv1.Block) {
 			Error:         fmt.Errorf("cannot have both range and condition in the same block, got: %v", v).Error(),
 		})
 	}
+	// the range value and the condition expression are evaluated before the
+	// items of the range exist, so they cannot refer to the range variables
 	if v.Range != nil {
 		if v.Range.Value != "" {
-			r.validateContext(oc, &ctrlcfgv1.Function{Block: v}, v.Range.Value)
+			r.validateContext(oc, &ctrlcfgv1.Function{}, v.Range.Value)
 		} else {
 			r.recordResult(Result{
 				OriginContext: oc,
 				Error:         fmt.Errorf("range value cannot be empty: %v", v).Error(),
 			})
 		}
+		if v.Range.Concurrency < 0 {
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error:         fmt.Errorf("range concurrency cannot be negative, got: %d", v.Range.Concurrency).Error(),
+			})
+		}
+		switch v.Range.ErrorPolicy {
+		case "", ctrlcfgv1.RangeErrorPolicyFailFast, ctrlcfgv1.RangeErrorPolicyCollect:
+		default:
+			r.recordResult(Result{
+				OriginContext: oc,
+				Error: fmt.Errorf("unknown range error policy %q, expecting %s or %s",
+					v.Range.ErrorPolicy, ctrlcfgv1.RangeErrorPolicyFailFast, ctrlcfgv1.RangeErrorPolicyCollect).Error(),
+			})
+		}
 		if v.Range.Range != nil || v.Range.Condition != nil {
 			r.validateBlock(oc, v.Range.Block)
 		}
 	}
 	if v.Condition != nil {
 		if v.Condition.Expression != "" {
-			r.validateContext(oc, &ctrlcfgv1.Function{Block: v}, v.Condition.Expression)
+			r.validateContext(oc, &ctrlcfgv1.Function{}, v.Condition.Expression)
 		} else {
 			r.recordResult(Result{
 				OriginContext: oc,
@@ -325,8 +507,30 @@ func (r *vs) validateBlock(oc *OriginContext, v ctrlcfgv1.Block) {
 //}
 
 func (r *vs) validateContext(oc *OriginContext, v *ctrlcfgv1.Function, s string) {
-	rfs := NewReferences()
-	refs := rfs.GetReferences(s)
+	refs, err := NewReferences().GetReferences(s)
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+		return
+	}
+	r.validateReferences(oc, v, refs, s)
+}
+
+func (r *vs) validateTemplateContext(oc *OriginContext, v *ctrlcfgv1.Function, s string) {
+	refs, err := NewReferences().GetTemplateReferences(s)
+	if err != nil {
+		r.recordResult(Result{
+			OriginContext: oc,
+			Error:         err.Error(),
+		})
+		return
+	}
+	r.validateReferences(oc, v, refs, s)
+}
+
+func (r *vs) validateReferences(oc *OriginContext, v *ctrlcfgv1.Function, refs []*Reference, s string) {
 	//fmt.Printf("validate ctxName: %s, value: %s, kind: %s, variable: %v\n", o.VertexName, s, value.Kind, value.Variable)
 	for _, ref := range refs {
 		switch ref.Kind {
@@ -335,7 +539,7 @@ func (r *vs) validateContext(oc *OriginContext, v *ctrlcfgv1.Function, s string)
 				//fmt.Printf("validate ctx: vertex %s, ref: %s, string: %s, function value: %v\n", oc.VertexName, ref, s, v.Block)
 				r.recordResult(Result{
 					OriginContext: oc,
-					Error:         fmt.Errorf("cannot use Key variables without a range statement").Error(),
+					Error:         fmt.Errorf("cannot use $%s without a range statement, got: %s", ref.Value, s).Error(),
 				})
 			}
 			/*
@@ -101,6 +101,7 @@ func (r *parser) processGvkObject(fnc *WalkConfig, oc *OriginContext, v *ctrlcfg
 				fnc.emptyPipelineFn(oc, v)
 			}
 		} else {
+			oc.PipelineIndex = r.getPipelineIndex(applyPipeline.Name)
 			fnc.walkPipeline(oc, applyPipeline)
 		}
 
@@ -111,11 +112,23 @@ func (r *parser) processGvkObject(fnc *WalkConfig, oc *OriginContext, v *ctrlcfg
 				fnc.emptyPipelineFn(oc, v)
 			}
 		} else {
+			oc.PipelineIndex = r.getPipelineIndex(deletePipeline.Name)
 			fnc.walkPipeline(oc, deletePipeline)
 		}
 	}
 }
 
+// getPipelineIndex returns the index of the pipeline in the pipelines of the
+// controller config
+func (r *parser) getPipelineIndex(name string) int {
+	for idx, pipeline := range r.cCfg.Spec.Properties.Pipelines {
+		if pipeline.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
 func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
 	pipelineName := v.Name
 	if fnc.pipelinePreHookFn != nil {
@@ -125,6 +138,7 @@ func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
 			Operation:      oc.Operation,
 			GVK:            oc.GVK,
 			Pipeline:       pipelineName,
+			PipelineIndex:  oc.PipelineIndex,
 			Origin:         oc.Origin,
 			VertexName:     oc.VertexName,
 		}
@@ -138,6 +152,7 @@ func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
 			Operation:      oc.Operation,
 			GVK:            oc.GVK,
 			Pipeline:       pipelineName,
+			PipelineIndex:  oc.PipelineIndex,
 			Origin:         OriginVariable,
 			VertexName:     vertexName,
 			LocalVars:      v.Vars,
@@ -152,6 +167,7 @@ func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
 			Operation:      oc.Operation,
 			GVK:            oc.GVK,
 			Pipeline:       pipelineName,
+			PipelineIndex:  oc.PipelineIndex,
 			Origin:         OriginFunction,
 			VertexName:     vertexName,
 			LocalVars:      v.Vars,
@@ -166,6 +182,7 @@ func (fnc *WalkConfig) walkPipeline(oc *OriginContext, v *ctrlcfgv1.Pipeline) {
 			Operation:      oc.Operation,
 			GVK:            oc.GVK,
 			Pipeline:       pipelineName,
+			PipelineIndex:  oc.PipelineIndex,
 			Origin:         oc.Origin,
 			VertexName:     oc.VertexName,
 		}
@@ -196,6 +213,8 @@ func (fnc *WalkConfig) walkFunctionElement(oc *OriginContext, v *ctrlcfgv1.Funct
 			fnc.functionFn(oc, &v.Function)
 		}
 
+		// the functions in the block can be blocks themselves, each level
+		// in the tree of blocks has its own DAG
 		for vertexName, v := range v.FunctionBlock {
 			oc := &OriginContext{
 				FOWS:            oc.FOWS,
@@ -203,12 +222,14 @@ func (fnc *WalkConfig) walkFunctionElement(oc *OriginContext, v *ctrlcfgv1.Funct
 				Operation:       oc.Operation,
 				GVK:             oc.GVK,
 				Pipeline:        oc.Pipeline,
+				PipelineIndex:   oc.PipelineIndex,
 				Origin:          oc.Origin,
 				Block:           true,
 				BlockIndex:      oc.BlockIndex + 1,
 				BlockVertexName: oc.VertexName,
+				BlockPath:       oc.getBlockPath(),
 				VertexName:      vertexName,
-				LocalVars:       oc.LocalVars,
+				LocalVars:       getLocalVars(oc.LocalVars, v),
 			}
 			fnc.walkFunctionElement(oc, v)
 		}
@@ -219,3 +240,20 @@ func (fnc *WalkConfig) walkFunctionElement(oc *OriginContext, v *ctrlcfgv1.Funct
 		}
 	}
 }
+
+// getLocalVars returns the local variables that are in scope of the function,
+// the local variables of the enclosing blocks are visible within the block
+// and the local variables of the function take precedence
+func getLocalVars(blockVars map[string]string, v *ctrlcfgv1.FunctionElement) map[string]string {
+	if v == nil || len(v.Vars) == 0 {
+		return blockVars
+	}
+	localVars := make(map[string]string, len(blockVars)+len(v.Vars))
+	for varName, expression := range blockVars {
+		localVars[varName] = expression
+	}
+	for varName, expression := range v.Vars {
+		localVars[varName] = expression
+	}
+	return localVars
+}
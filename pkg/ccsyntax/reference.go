@@ -1,6 +1,14 @@
 package ccsyntax
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/itchyny/gojq"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+)
 
 type ReferenceKind string
 
@@ -21,8 +29,10 @@ type Reference struct {
 }
 
 type References interface {
-	GetReferences(s string) []*Reference
-	//addReference(s string)
+	// GetReferences returns the free variables of a jq expression
+	GetReferences(s string) ([]*Reference, error)
+	// GetTemplateReferences returns the variables a go template refers to
+	GetTemplateReferences(s string) ([]*Reference, error)
 }
 
 type references struct {
@@ -35,29 +45,311 @@ func NewReferences() References {
 	}
 }
 
-func (r *references) GetReferences(s string) []*Reference {
-	var idx int
-	var found bool
-	for k, v := range s {
-		if v == '$' {
-			found = true
-			idx = k
-			continue
+// builtin jq variables that are not provided by the runtime
+var jqBuiltinVars = map[string]struct{}{
+	"$ENV":     {},
+	"$__loc__": {},
+}
+
+func (r *references) GetReferences(s string) ([]*Reference, error) {
+	// strings without variables dont need to be parsed, they can be
+	// a plain value e.g. a label key
+	if !strings.Contains(s, "$") {
+		return r.refs, nil
+	}
+	q, err := gojq.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse jq expression %q: %s", s, err.Error())
+	}
+	r.walkQuery(q, scope{})
+	return r.refs, nil
+}
+
+// scope holds the variables that are bound within the jq expression
+type scope map[string]struct{}
+
+// with returns a new scope extended with the supplied variables
+func (s scope) with(vars ...string) scope {
+	ns := make(scope, len(s)+len(vars))
+	for v := range s {
+		ns[v] = struct{}{}
+	}
+	for _, v := range vars {
+		ns[v] = struct{}{}
+	}
+	return ns
+}
+
+func (r *references) walkQuery(q *gojq.Query, s scope) {
+	if q == nil {
+		return
+	}
+	for _, fd := range q.FuncDefs {
+		// function parameters starting with $ are variables within the body
+		args := []string{}
+		for _, arg := range fd.Args {
+			if strings.HasPrefix(arg, "$") {
+				args = append(args, arg)
+			}
+		}
+		r.walkQuery(fd.Body, s.with(args...))
+	}
+	r.walkTerm(q.Term, s)
+	r.walkQuery(q.Left, s)
+	r.walkQuery(q.Right, s)
+}
+
+func (r *references) walkTerm(t *gojq.Term, s scope) {
+	if t == nil {
+		return
+	}
+	switch t.Type {
+	case gojq.TermTypeIndex:
+		r.walkIndex(t.Index, s)
+	case gojq.TermTypeFunc:
+		r.walkFunc(t.Func, s)
+	case gojq.TermTypeObject:
+		r.walkObject(t.Object, s)
+	case gojq.TermTypeArray:
+		if t.Array != nil {
+			r.walkQuery(t.Array.Query, s)
+		}
+	case gojq.TermTypeUnary:
+		if t.Unary != nil {
+			r.walkTerm(t.Unary.Term, s)
 		}
-		if (v == '.' || v == ' ' || v == ')' || v == '[') && found {
-			r.addReference(s[idx+1 : k])
-			found = false
+	case gojq.TermTypeFormat:
+		r.walkString(t.Str, s)
+	case gojq.TermTypeString:
+		r.walkString(t.Str, s)
+	case gojq.TermTypeIf:
+		r.walkIf(t.If, s)
+	case gojq.TermTypeTry:
+		if t.Try != nil {
+			r.walkQuery(t.Try.Body, s)
+			r.walkQuery(t.Try.Catch, s)
 		}
+	case gojq.TermTypeReduce:
+		if t.Reduce != nil {
+			r.walkTerm(t.Reduce.Term, s)
+			bs := s.with(r.walkPattern(t.Reduce.Pattern, s)...)
+			r.walkQuery(t.Reduce.Start, s)
+			r.walkQuery(t.Reduce.Update, bs)
+		}
+	case gojq.TermTypeForeach:
+		if t.Foreach != nil {
+			r.walkTerm(t.Foreach.Term, s)
+			bs := s.with(r.walkPattern(t.Foreach.Pattern, s)...)
+			r.walkQuery(t.Foreach.Start, s)
+			r.walkQuery(t.Foreach.Update, bs)
+			r.walkQuery(t.Foreach.Extract, bs)
+		}
+	case gojq.TermTypeLabel:
+		// labels are not variables
+		if t.Label != nil {
+			r.walkQuery(t.Label.Body, s)
+		}
+	case gojq.TermTypeQuery:
+		r.walkQuery(t.Query, s)
 	}
-	// if no . was found we store the complete value as a refrence value
-	if found {
-		r.addReference(s[idx+1:])
+
+	for _, sfx := range t.SuffixList {
+		r.walkIndex(sfx.Index, s)
+		if sfx.Bind != nil {
+			// term as $x | body -> $x is bound in the body
+			vars := []string{}
+			for _, p := range sfx.Bind.Patterns {
+				vars = append(vars, r.walkPattern(p, s)...)
+			}
+			r.walkQuery(sfx.Bind.Body, s.with(vars...))
+			// the remaining suffixes belong to the body of the binding
+			return
+		}
 	}
+}
 
-	return r.refs
+func (r *references) walkIndex(idx *gojq.Index, s scope) {
+	if idx == nil {
+		return
+	}
+	r.walkString(idx.Str, s)
+	r.walkQuery(idx.Start, s)
+	r.walkQuery(idx.End, s)
+}
+
+func (r *references) walkFunc(f *gojq.Func, s scope) {
+	if f == nil {
+		return
+	}
+	if strings.HasPrefix(f.Name, "$") {
+		r.addVariable(f.Name, s)
+	}
+	for _, arg := range f.Args {
+		r.walkQuery(arg, s)
+	}
+}
+
+func (r *references) walkObject(o *gojq.Object, s scope) {
+	if o == nil {
+		return
+	}
+	for _, kv := range o.KeyVals {
+		// {$x} is a shorthand for {x: $x}
+		if strings.HasPrefix(kv.Key, "$") {
+			r.addVariable(kv.Key, s)
+		}
+		r.walkString(kv.KeyString, s)
+		r.walkQuery(kv.KeyQuery, s)
+		if kv.Val != nil {
+			for _, q := range kv.Val.Queries {
+				r.walkQuery(q, s)
+			}
+		}
+	}
+}
+
+func (r *references) walkString(str *gojq.String, s scope) {
+	if str == nil {
+		return
+	}
+	// string interpolation e.g. "\($x)"
+	for _, q := range str.Queries {
+		r.walkQuery(q, s)
+	}
+}
+
+func (r *references) walkIf(i *gojq.If, s scope) {
+	if i == nil {
+		return
+	}
+	r.walkQuery(i.Cond, s)
+	r.walkQuery(i.Then, s)
+	for _, elif := range i.Elif {
+		r.walkQuery(elif.Cond, s)
+		r.walkQuery(elif.Then, s)
+	}
+	r.walkQuery(i.Else, s)
+}
+
+// walkPattern returns the variables a pattern binds, the key queries
+// of an object pattern are evaluated in the outer scope
+func (r *references) walkPattern(p *gojq.Pattern, s scope) []string {
+	if p == nil {
+		return nil
+	}
+	vars := []string{}
+	if p.Name != "" {
+		vars = append(vars, p.Name)
+	}
+	for _, ap := range p.Array {
+		vars = append(vars, r.walkPattern(ap, s)...)
+	}
+	for _, op := range p.Object {
+		if strings.HasPrefix(op.Key, "$") {
+			vars = append(vars, op.Key)
+		}
+		r.walkString(op.KeyString, s)
+		r.walkQuery(op.KeyQuery, s)
+		vars = append(vars, r.walkPattern(op.Val, s)...)
+	}
+	return vars
+}
+
+func (r *references) addVariable(name string, s scope) {
+	if _, ok := s[name]; ok {
+		return
+	}
+	if _, ok := jqBuiltinVars[name]; ok {
+		return
+	}
+	r.addReference(strings.TrimPrefix(name, "$"))
+}
+
+func (r *references) GetTemplateReferences(s string) ([]*Reference, error) {
+	tpl, err := template.New("default").Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse template: %s", err.Error())
+	}
+	for _, t := range tpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		r.walkTemplateNode(t.Tree.Root, true)
+	}
+	return r.refs, nil
+}
+
+// walkTemplateNode walks the template tree, root indicates if the dot
+// refers to the input of the template, within a range and with the dot
+// is rebound
+func (r *references) walkTemplateNode(n parse.Node, root bool) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, n := range n.Nodes {
+			r.walkTemplateNode(n, root)
+		}
+	case *parse.ActionNode:
+		r.walkTemplateNode(n.Pipe, root)
+	case *parse.TemplateNode:
+		r.walkTemplateNode(n.Pipe, root)
+	case *parse.IfNode:
+		r.walkTemplateBranch(&n.BranchNode, root, root)
+	case *parse.RangeNode:
+		r.walkTemplateBranch(&n.BranchNode, root, false)
+	case *parse.WithNode:
+		r.walkTemplateBranch(&n.BranchNode, root, false)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			r.walkTemplateNode(cmd, root)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			r.walkTemplateNode(arg, root)
+		}
+	case *parse.ChainNode:
+		r.walkTemplateNode(n.Node, root)
+	case *parse.FieldNode:
+		if root && len(n.Ident) > 0 {
+			r.addReference(n.Ident[0])
+		}
+	case *parse.VariableNode:
+		// $ always refers to the input of the template
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			r.addReference(n.Ident[1])
+		}
+	}
+}
+
+func (r *references) walkTemplateBranch(n *parse.BranchNode, root, listRoot bool) {
+	r.walkTemplateNode(n.Pipe, root)
+	r.walkTemplateNode(n.List, listRoot)
+	r.walkTemplateNode(n.ElseList, root)
+}
+
+// getTemplate returns the go template of a gotemplate function, the
+// resource takes precedence over the template, like in the function itself
+func getTemplate(v *ctrlcfgv1.Function) string {
+	if v.Type != ctrlcfgv1.GoTemplateType || v.Input == nil {
+		return ""
+	}
+	if len(v.Input.Resource.Raw) != 0 {
+		return string(v.Input.Resource.Raw)
+	}
+	return v.Input.Template
 }
 
 func (r *references) addReference(val string) {
+	for _, ref := range r.refs {
+		if ref.Value == val {
+			return
+		}
+	}
 	if val == ValueKey || val == KeyKey || val == IndexKey {
 		r.refs = append(r.refs, &Reference{
 			Kind:  RangeReferenceKind,
@@ -0,0 +1,143 @@
+package ccsyntax_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+)
+
+var _ = Describe("References", func() {
+	Describe("GetReferences", func() {
+		It("should not return a reference for an expression without variables", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("app")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(BeEmpty())
+		})
+
+		It("should return a variable once", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("$a.x + $a.y")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+			}))
+		})
+
+		It("should return a range variable as a range reference", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("$VALUE.name")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RangeReferenceKind, Value: "VALUE"},
+			}))
+		})
+
+		It("should not return the variables bound in the expression", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("$a as {name: $n, $spec} | [$n, $spec, $b]")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "b"},
+			}))
+		})
+
+		It("should return a variable used outside of its binding", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("($a as $x | $x) + $x")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "x"},
+			}))
+		})
+
+		It("should not return the variables bound by a reduce or a function parameter", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("def f($p): $p + $b; reduce $a[] as $x (0; . + f($x))")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "b"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+			}))
+		})
+
+		It("should return the variables in string interpolations, objects and indexes", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences(`{name: "\($a.name)", $b, c: $c[$d]}`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "b"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "c"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "d"},
+			}))
+		})
+
+		It("should return the variables in all the branches of an if", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("if $a then $b elif $c then $d else $e end")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(HaveLen(5))
+		})
+
+		It("should not return a builtin variable", func() {
+			refs, err := ccsyntax.NewReferences().GetReferences("$ENV.HOME")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(BeEmpty())
+		})
+
+		It("should return an error for an invalid expression", func() {
+			_, err := ccsyntax.NewReferences().GetReferences("$a |")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetTemplateReferences", func() {
+		It("should not return a reference for a template without fields", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences("name: test")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(BeEmpty())
+		})
+
+		It("should return the first field of a field chain", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences("name: {{ .a.name }}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+			}))
+		})
+
+		It("should not return the fields relative to the dot of a range", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences("{{ range .a }}{{ .name }}{{ $.b }}{{ end }}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "b"},
+			}))
+		})
+
+		It("should return the fields in the else branch of a with", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences("{{ with .a }}{{ .name }}{{ else }}{{ .b }}{{ end }}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+				{Kind: ccsyntax.RegularReferenceKind, Value: "b"},
+			}))
+		})
+
+		It("should return the fields used as function arguments", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences(`{{ printf "%s" .a }}`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RegularReferenceKind, Value: "a"},
+			}))
+		})
+
+		It("should return a range variable as a range reference", func() {
+			refs, err := ccsyntax.NewReferences().GetTemplateReferences("{{ .VALUE }}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refs).To(Equal([]*ccsyntax.Reference{
+				{Kind: ccsyntax.RangeReferenceKind, Value: "VALUE"},
+			}))
+		})
+
+		It("should return an error for an invalid template", func() {
+			_, err := ccsyntax.NewReferences().GetTemplateReferences("{{ .a ")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
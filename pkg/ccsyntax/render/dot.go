@@ -0,0 +1,74 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+)
+
+func renderDOT(w io.Writer, g *Graph) error {
+	dw := &dotWriter{}
+	dw.printf(0, "digraph %s {\n", dotQuote(g.Name))
+	dw.printf(1, "compound=true;\n")
+	dw.printf(1, "node [shape=box];\n")
+	ids := &idGenerator{}
+	for _, p := range g.Pipelines {
+		dw.startCluster(ids.next("c"), getPipelineLabel(p), 1)
+		writeDAG(dw, ids, p.DAG, 2)
+		dw.endCluster(1)
+	}
+	dw.printf(0, "}\n")
+	_, err := io.WriteString(w, dw.sb.String())
+	return err
+}
+
+type dotWriter struct {
+	sb strings.Builder
+}
+
+func (r *dotWriter) printf(depth int, format string, a ...any) {
+	r.sb.WriteString(strings.Repeat("  ", depth))
+	r.sb.WriteString(fmt.Sprintf(format, a...))
+}
+
+func (r *dotWriter) startCluster(id, label string, depth int) {
+	r.printf(depth, "subgraph cluster_%s {\n", id)
+	r.printf(depth+1, "label=%s;\n", dotQuote(label))
+}
+
+func (r *dotWriter) endCluster(depth int) {
+	r.printf(depth, "}\n")
+}
+
+func (r *dotWriter) vertex(id string, v *Vertex, depth int) {
+	attrs := fmt.Sprintf("label=%s", dotQuote(strings.Join(getVertexLabelLines(v), "\n")))
+	switch {
+	case v.Kind == string(rtdag.RootVertexKind):
+		attrs += ", shape=ellipse"
+	case v.Block != nil:
+		attrs += ", style=rounded"
+	}
+	r.printf(depth, "%s [%s];\n", id, attrs)
+}
+
+func (r *dotWriter) edge(from, to string, block bool, depth int) {
+	if block {
+		r.printf(depth, "%s -> %s [style=dashed];\n", from, to)
+		return
+	}
+	r.printf(depth, "%s -> %s;\n", from, to)
+}
+
+// dotQuote returns a quoted DOT string, newlines are rendered as left
+// aligned line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\l`)
+	if strings.Contains(s, `\l`) {
+		s += `\l`
+	}
+	return `"` + s + `"`
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/exec/output"
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+)
+
+// Graph is the representation of the compiled DAGs of a controller config,
+// all lists are sorted such that the representation is stable
+type Graph struct {
+	Name      string      `json:"name" yaml:"name"`
+	Pipelines []*Pipeline `json:"pipelines" yaml:"pipelines"`
+}
+
+// Pipeline is the DAG that runs for an operation on a for or watch resource
+type Pipeline struct {
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	FOW       string `json:"fow" yaml:"fow"`
+	Resource  string `json:"resource" yaml:"resource"`
+	Operation string `json:"operation" yaml:"operation"`
+	DAG       *DAG   `json:"dag" yaml:"dag"`
+}
+
+type DAG struct {
+	RootVertex string    `json:"rootVertex" yaml:"rootVertex"`
+	Vertices   []*Vertex `json:"vertices" yaml:"vertices"`
+	Edges      []*Edge   `json:"edges" yaml:"edges"`
+}
+
+type Vertex struct {
+	Name       string    `json:"name" yaml:"name"`
+	Kind       string    `json:"kind" yaml:"kind"`
+	Type       string    `json:"type" yaml:"type"`
+	References []string  `json:"references,omitempty" yaml:"references,omitempty"`
+	Outputs    []*Output `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	// Block is the DAG of a block vertex
+	Block *DAG `json:"block,omitempty" yaml:"block,omitempty"`
+}
+
+type Output struct {
+	Name        string `json:"name" yaml:"name"`
+	Internal    bool   `json:"internal" yaml:"internal"`
+	Conditioned bool   `json:"conditioned,omitempty" yaml:"conditioned,omitempty"`
+	Resource    string `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
+type Edge struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// GetGraph returns the graph of the apply and delete DAGs of the for and
+// watch resources in the config execution context
+func GetGraph(ceCtx ccsyntax.ConfigExecutionContext) *Graph {
+	g := &Graph{
+		Name:      ceCtx.GetName(),
+		Pipelines: []*Pipeline{},
+	}
+	for _, fow := range []ccsyntax.FOWS{ccsyntax.FOWFor, ccsyntax.FOWWatch} {
+		for gvk, od := range ceCtx.GetFOW(fow) {
+			gvk := gvk
+			for op, dctx := range od {
+				g.Pipelines = append(g.Pipelines, &Pipeline{
+					Name:      dctx.PipelineName,
+					FOW:       string(fow),
+					Resource:  meta.GVKToString(&gvk),
+					Operation: string(op),
+					DAG:       getDAG(dctx.DAG),
+				})
+			}
+		}
+	}
+	sort.SliceStable(g.Pipelines, func(i, j int) bool {
+		if g.Pipelines[i].FOW != g.Pipelines[j].FOW {
+			return g.Pipelines[i].FOW < g.Pipelines[j].FOW
+		}
+		if g.Pipelines[i].Resource != g.Pipelines[j].Resource {
+			return g.Pipelines[i].Resource < g.Pipelines[j].Resource
+		}
+		return g.Pipelines[i].Operation < g.Pipelines[j].Operation
+	})
+	return g
+}
+
+func getDAG(d rtdag.RuntimeDAG) *DAG {
+	rd := &DAG{
+		RootVertex: d.GetRootVertex(),
+		Vertices:   []*Vertex{},
+		Edges:      []*Edge{},
+	}
+	vertices := d.GetVertices()
+	vertexNames := make([]string, 0, len(vertices))
+	for vertexName := range vertices {
+		vertexNames = append(vertexNames, vertexName)
+	}
+	sort.Strings(vertexNames)
+
+	for _, vertexName := range vertexNames {
+		rd.Vertices = append(rd.Vertices, getVertex(vertexName, vertices[vertexName]))
+
+		downVertexNames := d.GetDownVertexes(vertexName)
+		sort.Strings(downVertexNames)
+		for _, downVertexName := range downVertexNames {
+			rd.Edges = append(rd.Edges, &Edge{From: vertexName, To: downVertexName})
+		}
+	}
+	return rd
+}
+
+func getVertex(vertexName string, v any) *Vertex {
+	vc, ok := v.(*rtdag.VertexContext)
+	if !ok {
+		return &Vertex{
+			Name: vertexName,
+			Kind: fmt.Sprintf("unknown %T", v),
+		}
+	}
+	rv := &Vertex{
+		Name: vertexName,
+		Kind: string(vc.Kind),
+		Type: string(vc.Function.Type),
+	}
+	if len(vc.References) != 0 {
+		rv.References = make([]string, len(vc.References))
+		copy(rv.References, vc.References)
+		sort.Strings(rv.References)
+	}
+	if vc.Outputs != nil {
+		rv.Outputs = getOutputs(vc.Outputs)
+	}
+	// the root vertex of a block DAG has the same context as the block
+	// vertex, the block DAG is only rendered for the block vertex
+	if vc.BlockDAG != nil && vc.Kind != rtdag.RootVertexKind {
+		rv.Block = getDAG(vc.BlockDAG)
+	}
+	return rv
+}
+
+func getOutputs(o output.Output) []*Output {
+	outputs := []*Output{}
+	for varName, v := range o.Get() {
+		ro := &Output{Name: varName}
+		if oi, ok := v.(*output.OutputInfo); ok {
+			ro.Internal = oi.Internal
+			ro.Conditioned = oi.Conditioned
+			if oi.GVK != nil {
+				ro.Resource = meta.GVKToString(oi.GVK)
+			}
+		}
+		outputs = append(outputs, ro)
+	}
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return outputs[i].Name < outputs[j].Name
+	})
+	return outputs
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+)
+
+func renderMermaid(w io.Writer, g *Graph) error {
+	mw := &mermaidWriter{}
+	mw.printf(0, "flowchart TD\n")
+	ids := &idGenerator{}
+	for _, p := range g.Pipelines {
+		mw.startCluster(ids.next("c"), getPipelineLabel(p), 1)
+		writeDAG(mw, ids, p.DAG, 2)
+		mw.endCluster(1)
+	}
+	_, err := io.WriteString(w, mw.sb.String())
+	return err
+}
+
+type mermaidWriter struct {
+	sb strings.Builder
+}
+
+func (r *mermaidWriter) printf(depth int, format string, a ...any) {
+	r.sb.WriteString(strings.Repeat("  ", depth))
+	r.sb.WriteString(fmt.Sprintf(format, a...))
+}
+
+func (r *mermaidWriter) startCluster(id, label string, depth int) {
+	r.printf(depth, "subgraph %s[%s]\n", id, mermaidQuote(label))
+}
+
+func (r *mermaidWriter) endCluster(depth int) {
+	r.printf(depth, "end\n")
+}
+
+func (r *mermaidWriter) vertex(id string, v *Vertex, depth int) {
+	label := mermaidQuote(strings.Join(getVertexLabelLines(v), "\n"))
+	switch {
+	case v.Kind == string(rtdag.RootVertexKind):
+		r.printf(depth, "%s([%s])\n", id, label)
+	case v.Block != nil:
+		r.printf(depth, "%s(%s)\n", id, label)
+	default:
+		r.printf(depth, "%s[%s]\n", id, label)
+	}
+}
+
+func (r *mermaidWriter) edge(from, to string, block bool, depth int) {
+	if block {
+		r.printf(depth, "%s -.-> %s\n", from, to)
+		return
+	}
+	r.printf(depth, "%s --> %s\n", from, to)
+}
+
+// mermaidQuote returns a quoted mermaid label, quotes are escaped as
+// entity codes and newlines are rendered as line breaks
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
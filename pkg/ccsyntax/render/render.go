@@ -0,0 +1,135 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+	FormatJSON    Format = "json"
+)
+
+// GetExtension returns the file extension commonly used for the format
+func (f Format) GetExtension() string {
+	switch f {
+	case FormatDOT:
+		return "dot"
+	case FormatMermaid:
+		return "mmd"
+	default:
+		return "json"
+	}
+}
+
+// Render writes the graph in the supplied format
+func Render(w io.Writer, g *Graph, f Format) error {
+	switch f {
+	case FormatDOT:
+		return renderDOT(w, g)
+	case FormatMermaid:
+		return renderMermaid(w, g)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	default:
+		return fmt.Errorf("unsupported render format, got: %s", f)
+	}
+}
+
+// getPipelineLabel returns the label of a pipeline cluster
+func getPipelineLabel(p *Pipeline) string {
+	label := fmt.Sprintf("%s %s %s", p.FOW, p.Resource, p.Operation)
+	if p.Name != "" {
+		label = fmt.Sprintf("%s: %s", p.Name, label)
+	}
+	return label
+}
+
+// getVertexLabelLines returns the lines of a vertex label
+// with the vertex name, function type, references and outputs
+func getVertexLabelLines(v *Vertex) []string {
+	lines := []string{v.Name}
+	if v.Type != "" {
+		lines = append(lines, fmt.Sprintf("type: %s", v.Type))
+	} else {
+		lines = append(lines, fmt.Sprintf("kind: %s", v.Kind))
+	}
+	if len(v.References) != 0 {
+		lines = append(lines, fmt.Sprintf("refs: %s", strings.Join(v.References, ", ")))
+	}
+	if len(v.Outputs) != 0 {
+		outputs := make([]string, 0, len(v.Outputs))
+		for _, o := range v.Outputs {
+			s := o.Name
+			if o.Resource != "" {
+				s = fmt.Sprintf("%s (%s)", o.Name, o.Resource)
+			}
+			if !o.Internal {
+				s = s + " [external]"
+			}
+			outputs = append(outputs, s)
+		}
+		lines = append(lines, fmt.Sprintf("outputs: %s", strings.Join(outputs, ", ")))
+	}
+	return lines
+}
+
+// idGenerator generates unique node and cluster ids, since vertex
+// names are only unique within a DAG
+type idGenerator struct {
+	idx int
+}
+
+func (r *idGenerator) next(prefix string) string {
+	id := fmt.Sprintf("%s%d", prefix, r.idx)
+	r.idx++
+	return id
+}
+
+// dagWriter renders the vertices and edges of a DAG, block vertices are
+// rendered as a nested cluster holding the DAG of the block
+type dagWriter interface {
+	startCluster(id, label string, depth int)
+	endCluster(depth int)
+	vertex(id string, v *Vertex, depth int)
+	edge(from, to string, block bool, depth int)
+}
+
+func writeDAG(dw dagWriter, ids *idGenerator, d *DAG, depth int) map[string]string {
+	vertexIDs := make(map[string]string, len(d.Vertices))
+	for _, v := range d.Vertices {
+		id := ids.next("v")
+		vertexIDs[v.Name] = id
+		dw.vertex(id, v, depth)
+	}
+	for _, v := range d.Vertices {
+		if v.Block == nil {
+			continue
+		}
+		dw.startCluster(ids.next("c"), fmt.Sprintf("block %s", v.Name), depth)
+		blockIDs := writeDAG(dw, ids, v.Block, depth+1)
+		dw.endCluster(depth)
+		if rootID, ok := blockIDs[v.Block.RootVertex]; ok {
+			dw.edge(vertexIDs[v.Name], rootID, true, depth)
+		}
+	}
+	for _, e := range d.Edges {
+		from, ok := vertexIDs[e.From]
+		if !ok {
+			continue
+		}
+		to, ok := vertexIDs[e.To]
+		if !ok {
+			continue
+		}
+		dw.edge(from, to, false, depth)
+	}
+	return vertexIDs
+}
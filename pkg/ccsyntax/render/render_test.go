@@ -0,0 +1,193 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax/render"
+	"sigs.k8s.io/yaml"
+)
+
+const config = `
+apiVersion: lcnc.yndd.io/v1
+kind: ControllerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  properties:
+    for:
+      topo:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Definition
+        applyPipelineRef: apply
+        deletePipelineRef: delete
+    pipelines:
+    - name: delete
+    - name: apply
+      vars:
+        name:
+          type: jq
+          input:
+            expression: $topo.metadata.name
+`
+
+// newGraph returns a graph with a root vertex, a task and a block
+func newGraph() *render.Graph {
+	return &render.Graph{
+		Name: "test",
+		Pipelines: []*render.Pipeline{
+			{
+				Name:      "apply",
+				FOW:       "for",
+				Resource:  "topo.yndd.io/v1alpha1/Definition",
+				Operation: "apply",
+				DAG: &render.DAG{
+					RootVertex: "topo",
+					Vertices: []*render.Vertex{
+						{
+							Name: "blk",
+							Kind: "function",
+							Type: "block",
+							Block: &render.DAG{
+								RootVertex: "blk",
+								Vertices: []*render.Vertex{
+									{Name: "blk", Kind: "root", Type: "block"},
+									{Name: "inner", Kind: "function", Type: "slice"},
+								},
+								Edges: []*render.Edge{{From: "blk", To: "inner"}},
+							},
+						},
+						{
+							Name:       "task",
+							Kind:       "function",
+							Type:       "jq",
+							References: []string{"topo"},
+							Outputs: []*render.Output{
+								{Name: "task", Internal: true},
+								{Name: "cm", Resource: "v1/ConfigMap"},
+							},
+						},
+						{Name: "topo", Kind: "root"},
+					},
+					Edges: []*render.Edge{
+						{From: "topo", To: "blk"},
+						{From: "topo", To: "task"},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Render", func() {
+	Describe("Render", func() {
+		It("should render the pipelines and blocks as DOT clusters", func() {
+			var b bytes.Buffer
+			Expect(render.Render(&b, newGraph(), render.FormatDOT)).To(Succeed())
+			Expect(b.String()).To(Equal(`digraph "test" {
+  compound=true;
+  node [shape=box];
+  subgraph cluster_c0 {
+    label="apply: for topo.yndd.io/v1alpha1/Definition apply";
+    v1 [label="blk\ltype: block\l", style=rounded];
+    v2 [label="task\ltype: jq\lrefs: topo\loutputs: task, cm (v1/ConfigMap) [external]\l"];
+    v3 [label="topo\lkind: root\l", shape=ellipse];
+    subgraph cluster_c4 {
+      label="block blk";
+      v5 [label="blk\ltype: block\l", shape=ellipse];
+      v6 [label="inner\ltype: slice\l"];
+      v5 -> v6;
+    }
+    v1 -> v5 [style=dashed];
+    v3 -> v1;
+    v3 -> v2;
+  }
+}
+`))
+		})
+
+		It("should render the pipelines and blocks as mermaid subgraphs", func() {
+			var b bytes.Buffer
+			Expect(render.Render(&b, newGraph(), render.FormatMermaid)).To(Succeed())
+			Expect(b.String()).To(Equal(`flowchart TD
+  subgraph c0["apply: for topo.yndd.io/v1alpha1/Definition apply"]
+    v1("blk<br/>type: block")
+    v2["task<br/>type: jq<br/>refs: topo<br/>outputs: task, cm (v1/ConfigMap) [external]"]
+    v3(["topo<br/>kind: root"])
+    subgraph c4["block blk"]
+      v5(["blk<br/>type: block"])
+      v6["inner<br/>type: slice"]
+      v5 --> v6
+    end
+    v1 -.-> v5
+    v3 --> v1
+    v3 --> v2
+  end
+`))
+		})
+
+		It("should render the graph as JSON", func() {
+			var b bytes.Buffer
+			Expect(render.Render(&b, newGraph(), render.FormatJSON)).To(Succeed())
+
+			g := &render.Graph{}
+			Expect(json.Unmarshal(b.Bytes(), g)).To(Succeed())
+			Expect(g).To(Equal(newGraph()))
+		})
+
+		It("should escape the quotes in the labels", func() {
+			g := newGraph()
+			g.Name = `a "test"`
+			var b bytes.Buffer
+			Expect(render.Render(&b, g, render.FormatDOT)).To(Succeed())
+			Expect(b.String()).To(HavePrefix(`digraph "a \"test\"" {`))
+		})
+
+		It("should return an error for an unsupported format", func() {
+			var b bytes.Buffer
+			Expect(render.Render(&b, newGraph(), render.Format("svg"))).NotTo(Succeed())
+		})
+	})
+
+	Describe("GetExtension", func() {
+		It("should return the file extension of the format", func() {
+			Expect(render.FormatDOT.GetExtension()).To(Equal("dot"))
+			Expect(render.FormatMermaid.GetExtension()).To(Equal("mmd"))
+			Expect(render.FormatJSON.GetExtension()).To(Equal("json"))
+		})
+	})
+
+	Describe("GetGraph", func() {
+		It("should return the sorted pipelines of the compiled controller config", func() {
+			cfg := &ctrlcfgv1.ControllerConfig{}
+			Expect(yaml.Unmarshal([]byte(config), cfg)).To(Succeed())
+			p, result := ccsyntax.NewParser(cfg)
+			Expect(result).To(BeEmpty())
+			ceCtx, result := p.Parse()
+			Expect(result).To(BeEmpty())
+
+			g := render.GetGraph(ceCtx)
+			Expect(g.Pipelines).To(HaveLen(2))
+			Expect(g.Pipelines[0].Name).To(Equal("apply"))
+			Expect(g.Pipelines[0].Operation).To(Equal("apply"))
+			Expect(g.Pipelines[1].Name).To(Equal("delete"))
+			Expect(g.Pipelines[1].Operation).To(Equal("delete"))
+
+			d := g.Pipelines[0].DAG
+			Expect(d.RootVertex).To(Equal("topo"))
+			Expect(d.Vertices).To(HaveLen(2))
+			Expect(d.Vertices[0].Name).To(Equal("name"))
+			Expect(d.Vertices[0].Type).To(Equal("jq"))
+			Expect(d.Vertices[0].References).To(Equal([]string{"topo"}))
+			Expect(d.Vertices[1].Name).To(Equal("topo"))
+			Expect(d.Vertices[1].Kind).To(Equal("root"))
+			Expect(d.Edges).To(Equal([]*render.Edge{{From: "topo", To: "name"}}))
+		})
+	})
+})
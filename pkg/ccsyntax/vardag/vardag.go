@@ -26,10 +26,11 @@ type varDAG struct {
 }
 
 type VariableContext struct {
-	VertexName      string // name of the vertex
-	OutputVertex    string // used for validation
-	BlockIndex      int    // used for validation and connectivity
-	BlockVertexName string // used for validation and connectivity
+	VertexName      string   // name of the vertex
+	OutputVertex    string   // used for validation
+	BlockIndex      int      // used for validation and connectivity
+	BlockVertexName string   // used for validation and connectivity
+	BlockPath       []string // blocks the output vertex is nested in, used for scoping
 }
 
 func (r *varDAG) AddVariable(s string, v *VariableContext) error {
@@ -0,0 +1,69 @@
+package dag
+
+import (
+	"sort"
+	"strings"
+)
+
+// vertex states used during the depth first search of the cycle detection
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// GetCycle returns the first cycle found in the graph as a list of vertices
+// where the first and the last vertex are the same, e.g. [a b c a].
+// An empty list is returned when the graph is acyclic.
+// The vertices are walked in sorted order so the result is deterministic.
+func (r *dag) GetCycle() []string {
+	vertexNames := make([]string, 0, len(r.GetVertices()))
+	for vertexName := range r.GetVertices() {
+		vertexNames = append(vertexNames, vertexName)
+	}
+	sort.Strings(vertexNames)
+
+	state := map[string]int{}
+	path := []string{}
+	for _, vertexName := range vertexNames {
+		if state[vertexName] != unvisited {
+			continue
+		}
+		if cycle := r.findCycle(vertexName, state, path); len(cycle) != 0 {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func (r *dag) findCycle(from string, state map[string]int, path []string) []string {
+	state[from] = visiting
+	path = append(path, from)
+
+	downVertices := r.GetDownVertexes(from)
+	sort.Strings(downVertices)
+	for _, to := range downVertices {
+		switch state[to] {
+		case visiting:
+			// the vertex is on the current path, so we found a cycle
+			for i, vertexName := range path {
+				if vertexName == to {
+					cycle := make([]string, 0, len(path)-i+1)
+					cycle = append(cycle, path[i:]...)
+					return append(cycle, to)
+				}
+			}
+		case unvisited:
+			if cycle := r.findCycle(to, state, path); len(cycle) != 0 {
+				return cycle
+			}
+		}
+	}
+	state[from] = visited
+	return nil
+}
+
+// CycleString renders a cycle as a -> b -> c -> a
+func CycleString(cycle []string) string {
+	return strings.Join(cycle, " -> ")
+}
@@ -0,0 +1,78 @@
+package dag_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/dag"
+)
+
+var _ = Describe("Dag", func() {
+	Describe("GetCycle", func() {
+		It("should not return a cycle for an empty graph", func() {
+			d := dag.New()
+			Expect(d.GetCycle()).To(BeEmpty())
+		})
+
+		It("should not return a cycle for an acyclic graph", func() {
+			d := dag.New()
+			Expect(d.AddVertex("a", nil)).To(Succeed())
+			Expect(d.AddVertex("b", nil)).To(Succeed())
+			Expect(d.AddVertex("c", nil)).To(Succeed())
+			d.Connect("a", "b")
+			d.Connect("a", "c")
+			d.Connect("b", "c")
+
+			Expect(d.GetCycle()).To(BeEmpty())
+		})
+
+		It("should return a vertex that depends on itself", func() {
+			d := dag.New()
+			Expect(d.AddVertex("a", nil)).To(Succeed())
+			Expect(d.AddVertex("b", nil)).To(Succeed())
+			d.Connect("a", "b")
+			d.Connect("b", "b")
+
+			Expect(d.GetCycle()).To(Equal([]string{"b", "b"}))
+		})
+
+		It("should return the path of the cycle starting and ending with the same vertex", func() {
+			d := dag.New()
+			Expect(d.AddVertex("a", nil)).To(Succeed())
+			Expect(d.AddVertex("b", nil)).To(Succeed())
+			Expect(d.AddVertex("c", nil)).To(Succeed())
+			Expect(d.AddVertex("d", nil)).To(Succeed())
+			d.Connect("a", "b")
+			d.Connect("b", "c")
+			d.Connect("c", "d")
+			d.Connect("d", "b")
+
+			Expect(d.GetCycle()).To(Equal([]string{"b", "c", "d", "b"}))
+		})
+
+		It("should return the first cycle in the sorted order of the vertices", func() {
+			d := dag.New()
+			Expect(d.AddVertex("a", nil)).To(Succeed())
+			Expect(d.AddVertex("b", nil)).To(Succeed())
+			Expect(d.AddVertex("c", nil)).To(Succeed())
+			Expect(d.AddVertex("d", nil)).To(Succeed())
+			d.Connect("c", "d")
+			d.Connect("d", "c")
+			d.Connect("a", "b")
+			d.Connect("b", "a")
+
+			for i := 0; i < 10; i++ {
+				Expect(d.GetCycle()).To(Equal([]string{"a", "b", "a"}))
+			}
+		})
+	})
+
+	Describe("CycleString", func() {
+		It("should render an empty string when there is no cycle", func() {
+			Expect(dag.CycleString(nil)).To(Equal(""))
+		})
+
+		It("should render the vertices of the cycle", func() {
+			Expect(dag.CycleString([]string{"a", "b", "c", "a"})).To(Equal("a -> b -> c -> a"))
+		})
+	})
+})
@@ -19,6 +19,7 @@ type DAG interface {
 	GetDownVertexes(from string) []string
 	GetUpVertexes(from string) []string
 	TransitiveReduction()
+	GetCycle() []string
 }
 
 // used for returning
@@ -2,6 +2,8 @@ package executor
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,30 +12,52 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+// DefaultMaxWorkers is the default number of vertex functions an
+// executor runs concurrently
+const DefaultMaxWorkers = 8
+
 type Executor interface {
 	Run(ctx context.Context)
 }
 
-type VertexFuntionRunFn func(ctx context.Context, vertexName string, vertexContext any) bool
+// VertexOutcome is the outcome of the execution of a vertex
+type VertexOutcome string
+
+const (
+	VertexSucceeded VertexOutcome = "succeeded"
+	VertexFailed    VertexOutcome = "failed"
+	// VertexSkipped indicates the function of the vertex did not run to
+	// completion e.g. because its condition is false or because one of the
+	// vertices it depends on did not succeed. A skip propagates to all the
+	// dependent vertices.
+	VertexSkipped VertexOutcome = "skipped"
+)
+
+type VertexFuntionRunFn func(ctx context.Context, vertexName string, vertexContext any) VertexOutcome
+type VertexSkipFn func(vertexName string, vertexContext any, reason string)
 type ExecPostRunFn func(start, finish time.Time, success bool)
 
 type Config struct {
 	Name string
 	From string
+	// MaxWorkers is the maximum number of vertex functions that run
+	// concurrently, defaults to DefaultMaxWorkers
+	MaxWorkers int
 	//Handlers
 	VertexFuntionRunFn VertexFuntionRunFn
-	ExecPostRunFn      ExecPostRunFn
+	// VertexSkipFn is called for every vertex that is skipped because a
+	// dependency did not succeed or because it is not reachable from From
+	VertexSkipFn  VertexSkipFn
+	ExecPostRunFn ExecPostRunFn
 }
 
 func New(d dag.DAG, cfg *Config) Executor {
 	s := &exec{
-		cfg:       cfg,
-		d:         d,
-		m:         sync.RWMutex{},
-		execMap:   map[string]*execContext{},
-		fnDoneMap: map[string]chan bool{},
+		cfg:     cfg,
+		d:       d,
+		execMap: map[string]*execContext{},
 
-		l: ctrl.Log.WithName("executor"),
+		l: ctrl.Log.WithName("executor").WithValues("execName", cfg.Name),
 	}
 
 	// initialize the initial data in the executor
@@ -45,150 +69,201 @@ type exec struct {
 	d   dag.DAG
 	cfg *Config
 
-	// cancelFn
-	cancelFn context.CancelFunc
-
-	// used during the Walk func
-	m         sync.RWMutex
-	execMap   map[string]*execContext
-	fnDoneMap map[string]chan bool
+	// used during the execution, only accessed by the scheduler
+	execMap map[string]*execContext
 	// logging
 	l logr.Logger
 }
 
-// init initializes the executor with channels and cancel context
-// so it is prepaared to execute the dependency map
+// vertexDone is sent by a worker when a vertex function completed
+type vertexDone struct {
+	vertexName string
+	outcome    VertexOutcome
+}
+
+// init initializes the execution state of the vertices that are reachable
+// from the start vertex, a vertex is pending on each of its dependencies.
+// A vertex with a dependency that is not reachable is skipped as the
+// dependency never runs.
 func (r *exec) init() {
-	r.execMap = map[string]*execContext{}
-	for vertexName, v := range r.d.GetVertices() {
-		//fmt.Printf("executor init vertexName: %s\n", vertexName)
-		r.l.Info("init", "vertexName", vertexName)
-		r.execMap[vertexName] = &execContext{
-			execName:      r.cfg.Name,
-			vertexName:    vertexName,
-			vertexContext: v,
-			doneChs:       make(map[string]chan bool), //snd
-			depChs:        make(map[string]chan bool), //rcv
-			deps:          make([]string, 0),
-			// callback to gather the result
-			//recordResult: r.cfg.Result.Add,
-			//recordOutput: r.cfg.Output.Add,
-			vertexFuntionRunFn: r.cfg.VertexFuntionRunFn,
-			l:                  ctrl.Log.WithName("execContext").WithValues("execName", r.cfg.Name, "vertexName", vertexName),
+	r.addExecContext(r.cfg.From)
+	for vertexName, eCtx := range r.execMap {
+		upVertexNames := r.d.GetUpVertexes(vertexName)
+		sort.Strings(upVertexNames)
+		for _, depVertexName := range upVertexNames {
+			if _, ok := r.execMap[depVertexName]; ok {
+				eCtx.pending++
+				continue
+			}
+			if eCtx.skipReason == "" {
+				eCtx.skipReason = fmt.Sprintf("dependency %s not reachable from %s", depVertexName, r.cfg.From)
+			}
 		}
 	}
-	// build the channel matrix to signal dependencies through channels
-	// for every dependency (upstream relationship between verteces)
-	// create a channel
-	// add the channel to the upstreamm vertex doneCh map ->
-	// usedto signal/send the vertex finished the function/job
-	// add the channel to the downstream vertex depCh map ->
-	// used to wait for the upstream vertex to signal the fn/job is done
-	for vertexName, wCtx := range r.execMap {
-		// only run these channels when we want to add dependency validation
-		for _, depVertexName := range r.d.GetUpVertexes(vertexName) {
-			//fmt.Printf("vertexName: %s, depBVertexName: %s\n", vertexName, depVertexName)
-			depCh := make(chan bool)
-			r.execMap[depVertexName].AddDoneCh(vertexName, depCh) // send when done
-			wCtx.AddDepCh(depVertexName, depCh)                   // rcvr when done
-		}
-		wCtx.deps = r.d.GetUpVertexes(vertexName)
-		doneFnCh := make(chan bool)
-		wCtx.doneFnCh = doneFnCh
-		r.fnDoneMap[vertexName] = doneFnCh
+}
+
+func (r *exec) addExecContext(vertexName string) {
+	if _, ok := r.execMap[vertexName]; ok || !r.d.VertexExists(vertexName) {
+		return
+	}
+	r.l.Info("init", "vertexName", vertexName)
+	r.execMap[vertexName] = &execContext{
+		vertexName:         vertexName,
+		vertexContext:      r.d.GetVertex(vertexName),
+		vertexFuntionRunFn: r.cfg.VertexFuntionRunFn,
+	}
+	for _, downVertexName := range r.d.GetDownVertexes(vertexName) {
+		r.addExecContext(downVertexName)
 	}
 }
 
 func (r *exec) Run(ctx context.Context) {
-	from := r.cfg.From
 	start := time.Now()
-	ctx, cancelFn := context.WithCancel(ctx)
-	r.cancelFn = cancelFn
-	success := r.execute(ctx, from, true)
+	// a cyclic graph never gets all its dependencies completed, so we refuse to run it
+	if cycle := r.d.GetCycle(); len(cycle) != 0 {
+		r.l.Error(fmt.Errorf("cycle detected: %s", dag.CycleString(cycle)), "cannot execute a cyclic graph")
+		r.cfg.ExecPostRunFn(start, time.Now(), false)
+		return
+	}
+	success := r.execute(ctx)
 	finish := time.Now()
 
 	// handler to execute a final action e.g. recording the overall result
 	r.cfg.ExecPostRunFn(start, finish, success)
 }
 
-func (r *exec) execute(ctx context.Context, from string, init bool) bool {
-	r.l.Info("execute", "from", from, "init", init)
-	//fmt.Printf("execute from: %s init: %t\n", from, init)
-	wCtx := r.getExecContext(from)
-	//fmt.Printf("execute getExecContext from: %s init: %t, wCtx: %#v\n", from, init, wCtx)
-	// avoid scheduling a vertex that is already visted
-	if !wCtx.isVisted() {
-		wCtx.m.Lock()
-		wCtx.visited = time.Now()
-		wCtx.m.Unlock()
-		// execute the vertex function
-		r.l.Info("execute scheduled vertex", "vertexname", wCtx.vertexName)
-		//fmt.Printf("execute scheduled vertex: %s\n", wCtx.vertexName)
+// execute runs the vertices in topological order. A vertex is ready when all
+// its dependencies completed, ready vertices are handed to a bounded pool of
+// workers and the scheduler is driven by the completion of the workers only.
+// A vertex for which a dependency did not succeed, or which gets ready after
+// the context got cancelled, is skipped and the skip propagates to its
+// dependents. A failed vertex does not stop the independent vertices. The
+// execution succeeds when no vertex failed.
+func (r *exec) execute(ctx context.Context) bool {
+	if _, ok := r.execMap[r.cfg.From]; !ok {
+		r.l.Error(fmt.Errorf("vertex %s not found", r.cfg.From), "cannot execute")
+		return false
+	}
+	r.skipUnreachable()
+
+	maxWorkers := r.cfg.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = DefaultMaxWorkers
+	}
+	if maxWorkers > len(r.execMap) {
+		maxWorkers = len(r.execMap)
+	}
+
+	workCh := make(chan *execContext)
+	doneCh := make(chan vertexDone)
+	var wg sync.WaitGroup
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
 		go func() {
-			if !r.dependenciesFinished(wCtx.depChs) {
-				//fmt.Printf("%s not finished\n", from)
-				r.l.Info("not finished", "vertexname", from)
+			defer wg.Done()
+			for eCtx := range workCh {
+				doneCh <- vertexDone{vertexName: eCtx.vertexName, outcome: eCtx.run(ctx)}
 			}
-			if !wCtx.waitDependencies(ctx) {
-				// TODO gather info why the failure occured
-				return
-			}
-			// execute the vertex function
-			wCtx.run(ctx)
 		}()
 	}
-	// continue walking the graph
-	for _, downEdge := range r.d.GetDownVertexes(from) {
-		go func(downEdge string) {
-			r.execute(ctx, downEdge, false)
-		}(downEdge)
+	defer func() {
+		close(workCh)
+		wg.Wait()
+	}()
+
+	success := true
+	ready := []string{r.cfg.From}
+	running := 0
+	completed := 0
+	for completed < len(r.execMap) {
+		// dispatch the ready vertices as long as a worker is available,
+		// a worker is idle when less vertices are running than workers
+		for len(ready) > 0 {
+			eCtx := r.execMap[ready[0]]
+			if eCtx.skipReason == "" && ctx.Err() != nil {
+				eCtx.skipReason = fmt.Sprintf("cancelled: %s", ctx.Err())
+			}
+			if eCtx.skipReason != "" {
+				ready = ready[1:]
+				r.l.Info("skip", "vertexName", eCtx.vertexName, "reason", eCtx.skipReason)
+				if r.cfg.VertexSkipFn != nil {
+					r.cfg.VertexSkipFn(eCtx.vertexName, eCtx.vertexContext, eCtx.skipReason)
+				}
+				ready = append(ready, r.complete(eCtx, VertexSkipped)...)
+				completed++
+				continue
+			}
+			if running == maxWorkers {
+				break
+			}
+			ready = ready[1:]
+			r.l.Info("schedule", "vertexName", eCtx.vertexName)
+			workCh <- eCtx
+			running++
+		}
+		if completed == len(r.execMap) {
+			break
+		}
+
+		d := <-doneCh
+		running--
+		completed++
+		r.l.Info("done", "vertexName", d.vertexName, "outcome", d.outcome)
+		if d.outcome == VertexFailed {
+			success = false
+		}
+		ready = append(ready, r.complete(r.execMap[d.vertexName], d.outcome)...)
 	}
-	if init {
-		return r.waitFunctionCompletion(ctx)
+	r.l.Info("execution finished", "success", success)
+	if ctx.Err() != nil {
+		// called when the controller gets cancelled
+		return false
 	}
-	return true
+	return success
 }
 
-func (r *exec) getExecContext(s string) *execContext {
-	r.m.RLock()
-	defer r.m.RUnlock()
-	return r.execMap[s]
-}
-
-func (r *exec) dependenciesFinished(dep map[string]chan bool) bool {
-	for vertexName := range dep {
-		if !r.getExecContext(vertexName).isFinished() {
-			return false
+// skipUnreachable records the vertices that are not reachable from the start
+// vertex as skipped, they never run
+func (r *exec) skipUnreachable() {
+	vertexNames := []string{}
+	for vertexName := range r.d.GetVertices() {
+		if _, ok := r.execMap[vertexName]; !ok {
+			vertexNames = append(vertexNames, vertexName)
+		}
+	}
+	sort.Strings(vertexNames)
+	for _, vertexName := range vertexNames {
+		reason := fmt.Sprintf("not reachable from %s", r.cfg.From)
+		r.l.Info("skip", "vertexName", vertexName, "reason", reason)
+		if r.cfg.VertexSkipFn != nil {
+			r.cfg.VertexSkipFn(vertexName, r.d.GetVertex(vertexName), reason)
 		}
 	}
-	return true
 }
 
-func (r *exec) waitFunctionCompletion(ctx context.Context) bool {
-	//fmt.Printf("main walk wait waiting for function completion...\n")
-	r.l.Info("main walk wait waiting for function completion...")
-DepSatisfied:
-	for vertexName, doneFnCh := range r.fnDoneMap {
-		for {
-			select {
-			case d, ok := <-doneFnCh:
-				r.l.Info("main walk wait rcvd fn done", "from", vertexName, "success", d, "ok", ok)
-				//fmt.Printf("main walk wait rcvd fn done from %s, d: %t, ok: %t\n", vertexName, d, ok)
-				if !d {
-					r.cancelFn()
-					return false
-				}
-				continue DepSatisfied
-			case <-ctx.Done():
-				// called when the controller gets cancelled
-				return false
-			case <-time.After(time.Second * 5):
-				r.l.Info("main walk wait timeout, waiting", "for", vertexName)
-				//fmt.Printf("main walk wait timeout, waiting for %s\n", vertexName)
-			}
+// complete records the outcome of the vertex and returns the dependent
+// vertices that became ready, a dependent is skipped when the vertex did
+// not succeed
+func (r *exec) complete(eCtx *execContext, outcome VertexOutcome) []string {
+	eCtx.outcome = outcome
+	eCtx.finished = time.Now()
+
+	downVertexNames := r.d.GetDownVertexes(eCtx.vertexName)
+	// sorted to make the schedule deterministic
+	sort.Strings(downVertexNames)
+	ready := []string{}
+	for _, downVertexName := range downVertexNames {
+		downCtx, ok := r.execMap[downVertexName]
+		if !ok {
+			continue
+		}
+		if outcome != VertexSucceeded && downCtx.skipReason == "" {
+			downCtx.skipReason = fmt.Sprintf("dependency %s %s", eCtx.vertexName, outcome)
+		}
+		downCtx.pending--
+		if downCtx.pending == 0 {
+			ready = append(ready, downVertexName)
 		}
 	}
-	r.l.Info("main walk wait function completion waiting finished - bye !")
-	//fmt.Printf("main walk wait function completion waiting finished - bye !\n")
-	return true
+	return ready
 }
@@ -0,0 +1,13 @@
+package executor_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestExecutor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Executor Suite")
+}
@@ -0,0 +1,179 @@
+package executor_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/dag"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
+)
+
+// run records the vertices that run and the vertices that are skipped
+type run struct {
+	m       sync.Mutex
+	ran     []string
+	skipped map[string]string
+	success bool
+}
+
+func newDAG(vertices []string, edges [][2]string) dag.DAG {
+	d := dag.New()
+	for _, v := range vertices {
+		Expect(d.AddVertex(v, nil)).To(Succeed())
+	}
+	for _, e := range edges {
+		d.Connect(e[0], e[1])
+	}
+	return d
+}
+
+// execute runs the dag from the root vertex, the outcome of a vertex that
+// runs is succeeded unless it is in outcomes
+func execute(d dag.DAG, outcomes map[string]executor.VertexOutcome) *run {
+	r := &run{skipped: map[string]string{}}
+	executor.New(d, &executor.Config{
+		Name: "test",
+		From: "root",
+		VertexFuntionRunFn: func(ctx context.Context, vertexName string, vertexContext any) executor.VertexOutcome {
+			r.m.Lock()
+			defer r.m.Unlock()
+			r.ran = append(r.ran, vertexName)
+			if o, ok := outcomes[vertexName]; ok {
+				return o
+			}
+			return executor.VertexSucceeded
+		},
+		VertexSkipFn: func(vertexName string, vertexContext any, reason string) {
+			r.m.Lock()
+			defer r.m.Unlock()
+			r.skipped[vertexName] = reason
+		},
+		ExecPostRunFn: func(start, finish time.Time, success bool) {
+			r.success = success
+		},
+	}).Run(context.Background())
+	return r
+}
+
+var _ = Describe("Executor", func() {
+	Describe("Run", func() {
+		diamond := [][2]string{{"root", "a"}, {"root", "b"}, {"a", "c"}, {"b", "c"}}
+
+		It("should run the vertices of a linear graph in order", func() {
+			r := execute(newDAG([]string{"root", "a", "b"}, [][2]string{{"root", "a"}, {"a", "b"}}), nil)
+			Expect(r.ran).To(Equal([]string{"root", "a", "b"}))
+			Expect(r.skipped).To(BeEmpty())
+			Expect(r.success).To(BeTrue())
+		})
+
+		It("should run a vertex after all its dependencies", func() {
+			r := execute(newDAG([]string{"root", "a", "b", "c"}, diamond), nil)
+			Expect(r.ran).To(ConsistOf("root", "a", "b", "c"))
+			Expect(r.ran[0]).To(Equal("root"))
+			Expect(r.ran[3]).To(Equal("c"))
+			Expect(r.skipped).To(BeEmpty())
+			Expect(r.success).To(BeTrue())
+		})
+
+		It("should skip the dependents of a failed vertex", func() {
+			edges := append(diamond, [2]string{"c", "d"})
+			r := execute(newDAG([]string{"root", "a", "b", "c", "d"}, edges), map[string]executor.VertexOutcome{
+				"a": executor.VertexFailed,
+			})
+			Expect(r.ran).To(ConsistOf("root", "a", "b"))
+			Expect(r.skipped).To(Equal(map[string]string{
+				"c": "dependency a failed",
+				"d": "dependency c skipped",
+			}))
+			Expect(r.success).To(BeFalse())
+		})
+
+		It("should skip the dependents of a skipped vertex without failing", func() {
+			r := execute(newDAG([]string{"root", "a", "b", "c"}, diamond), map[string]executor.VertexOutcome{
+				"b": executor.VertexSkipped,
+			})
+			Expect(r.ran).To(ConsistOf("root", "a", "b"))
+			Expect(r.skipped).To(Equal(map[string]string{
+				"c": "dependency b skipped",
+			}))
+			Expect(r.success).To(BeTrue())
+		})
+
+		It("should run the independent vertices when a vertex fails", func() {
+			r := execute(newDAG([]string{"root", "a", "b"}, [][2]string{{"root", "a"}, {"root", "b"}}), map[string]executor.VertexOutcome{
+				"a": executor.VertexFailed,
+			})
+			Expect(r.ran).To(ConsistOf("root", "a", "b"))
+			Expect(r.skipped).To(BeEmpty())
+			Expect(r.success).To(BeFalse())
+		})
+
+		It("should skip the vertices that are not reachable", func() {
+			r := execute(newDAG([]string{"root", "a", "x", "y"}, [][2]string{{"root", "a"}, {"x", "y"}, {"a", "y"}}), nil)
+			Expect(r.ran).To(Equal([]string{"root", "a"}))
+			Expect(r.skipped).To(Equal(map[string]string{
+				"x": "not reachable from root",
+				"y": "dependency x not reachable from root",
+			}))
+			Expect(r.success).To(BeTrue())
+		})
+
+		It("should not run more vertices concurrently than the max workers", func() {
+			vertices := []string{"root", "a", "b", "c", "d", "e"}
+			edges := [][2]string{{"root", "a"}, {"root", "b"}, {"root", "c"}, {"root", "d"}, {"root", "e"}}
+			var m sync.Mutex
+			running, maxRunning := 0, 0
+			executor.New(newDAG(vertices, edges), &executor.Config{
+				Name:       "test",
+				From:       "root",
+				MaxWorkers: 2,
+				VertexFuntionRunFn: func(ctx context.Context, vertexName string, vertexContext any) executor.VertexOutcome {
+					m.Lock()
+					running++
+					if running > maxRunning {
+						maxRunning = running
+					}
+					m.Unlock()
+					time.Sleep(10 * time.Millisecond)
+					m.Lock()
+					running--
+					m.Unlock()
+					return executor.VertexSucceeded
+				},
+				ExecPostRunFn: func(start, finish time.Time, success bool) {},
+			}).Run(context.Background())
+			Expect(maxRunning).To(Equal(2))
+		})
+
+		It("should skip the vertices that get ready after the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			skipped := map[string]string{}
+			success := true
+			executor.New(newDAG([]string{"root", "a"}, [][2]string{{"root", "a"}}), &executor.Config{
+				Name: "test",
+				From: "root",
+				VertexFuntionRunFn: func(ctx context.Context, vertexName string, vertexContext any) executor.VertexOutcome {
+					cancel()
+					return executor.VertexSucceeded
+				},
+				VertexSkipFn: func(vertexName string, vertexContext any, reason string) {
+					skipped[vertexName] = reason
+				},
+				ExecPostRunFn: func(start, finish time.Time, ok bool) {
+					success = ok
+				},
+			}).Run(ctx)
+			Expect(skipped).To(Equal(map[string]string{"a": "cancelled: context canceled"}))
+			Expect(success).To(BeFalse())
+		})
+
+		It("should not run a cyclic graph", func() {
+			r := execute(newDAG([]string{"root", "a", "b"}, [][2]string{{"root", "a"}, {"a", "b"}, {"b", "a"}}), nil)
+			Expect(r.ran).To(BeEmpty())
+			Expect(r.success).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package controllerconfig
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestControllerconfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controllerconfig Suite")
+}
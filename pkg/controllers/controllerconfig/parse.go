@@ -0,0 +1,47 @@
+package controllerconfig
+
+import (
+	"fmt"
+
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// parse validates and parses the controller config and returns the
+// config execution context and the external resources it uses.
+// When the result is not empty the reason indicates the failing phase.
+func parse(cfg *ctrlcfgv1.ControllerConfig) (ccsyntax.ConfigExecutionContext, []*schema.GroupVersionKind, ctrlcfgv1.ConditionReason, []ccsyntax.Result) {
+	if cfg.Spec.Properties == nil {
+		return nil, nil, ctrlcfgv1.ConditionReasonSyntaxError, []ccsyntax.Result{{
+			Error: "a controller config must have properties",
+		}}
+	}
+
+	p, result := ccsyntax.NewParser(cfg)
+	if len(result) != 0 {
+		return nil, nil, ctrlcfgv1.ConditionReasonSyntaxError, result
+	}
+
+	ceCtx, result := p.Parse()
+	if len(result) != 0 {
+		return nil, nil, ctrlcfgv1.ConditionReasonParseError, result
+	}
+
+	gvks, result := p.GetExternalResources()
+	if len(result) != 0 {
+		return nil, nil, ctrlcfgv1.ConditionReasonParseError, result
+	}
+	return ceCtx, gvks, "", nil
+}
+
+// validateMapping validates if the gvks can be resolved in the api server
+func validateMapping(mapper meta.RESTMapper, gvks []*schema.GroupVersionKind) error {
+	for _, gvk := range gvks {
+		if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			return fmt.Errorf("gvk: %s, err: %s", gvk.String(), err.Error())
+		}
+	}
+	return nil
+}
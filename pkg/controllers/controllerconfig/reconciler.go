@@ -0,0 +1,352 @@
+package controllerconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/builder"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/controller"
+	"github.com/yndd/lcnc-runtime/pkg/controllers/reconciler"
+	lcncevent "github.com/yndd/lcnc-runtime/pkg/event"
+	"github.com/yndd/lcnc-runtime/pkg/manager"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	controllerName = "controllerconfig"
+	// errors
+	errGetCtrlCfg     = "cannot get controller config"
+	errBuildCtrl      = "cannot build controller"
+	errResolveMapping = "cannot resolve gvk mapping in api server"
+	errUpdateStatus   = "cannot update status"
+)
+
+type Config struct {
+	Mgr manager.Manager
+	// PollInterval is passed to the reconciler of each controller unless
+	// the ControllerConfig overrides it
+	PollInterval time.Duration
+	// MaxConcurrentReconciles is applied to each controller built
+	// from a ControllerConfig
+	MaxConcurrentReconciles int
+	// MaxWorkers is the maximum number of functions that run concurrently
+	// in a pipeline of each controller
+	MaxWorkers int
+	// AllowWasm enables wasm functions in the pipelines of each controller
+	AllowWasm bool
+}
+
+// Setup adds a controller to the manager that watches the ControllerConfig
+// resources and builds, starts, replaces and stops a lcnc controller
+// for each of them. The lcnc controllers are run with the supplied context.
+func Setup(ctx context.Context, c *Config) error {
+	r := newReconciler(ctx, c)
+
+	ctrl, err := controller.New(controllerName, c.Mgr, controller.Options{
+		Reconciler: r,
+	})
+	if err != nil {
+		return err
+	}
+	// status updates should not trigger a rebuild of the controller
+	if err := ctrl.Watch(
+		&source.Kind{Type: &ctrlcfgv1.ControllerConfig{}},
+		&handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{},
+	); err != nil {
+		return err
+	}
+	// the controller configs of the controllers that stopped with an error
+	// are requeued through generic events
+	return ctrl.Watch(&source.Channel{Source: r.ge}, &handler.EnqueueRequestForObject{})
+}
+
+func New(ctx context.Context, c *Config) reconcile.Reconciler {
+	return newReconciler(ctx, c)
+}
+
+func newReconciler(ctx context.Context, c *Config) *ctrlcfgReconciler {
+	return &ctrlcfgReconciler{
+		ctx:          ctx,
+		mgr:          c.Mgr,
+		client:       c.Mgr.GetClient(),
+		pollInterval: c.PollInterval,
+		concurrency:  c.MaxConcurrentReconciles,
+		maxWorkers:   c.MaxWorkers,
+		allowWasm:    c.AllowWasm,
+		controllers:  map[types.NamespacedName]*runningController{},
+		ge:           make(chan event.GenericEvent),
+		l:            ctrl.Log.WithName("controllerconfig reconcile"),
+	}
+}
+
+type ctrlcfgReconciler struct {
+	// ctx is the context the lcnc controllers are started with
+	ctx          context.Context
+	mgr          manager.Manager
+	client       client.Client
+	pollInterval time.Duration
+	concurrency  int
+	maxWorkers   int
+	allowWasm    bool
+
+	m           sync.Mutex
+	controllers map[types.NamespacedName]*runningController
+	// ge requeues the controller config of a controller that stopped
+	// with an error
+	ge chan event.GenericEvent
+
+	l logr.Logger
+}
+
+// runningController keeps track of a lcnc controller that was started
+// for a specific generation of a ControllerConfig
+type runningController struct {
+	generation int64
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func (r *ctrlcfgReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info("reconcile start...")
+
+	cfg := &ctrlcfgv1.ControllerConfig{}
+	if err := r.client.Get(ctx, req.NamespacedName, cfg); err != nil {
+		if meta.IgnoreNotFound(err) == nil {
+			// the controller config no longer exists, stop the controller
+			r.stop(req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		l.Error(err, errGetCtrlCfg)
+		return reconcile.Result{}, errors.Wrap(err, errGetCtrlCfg)
+	}
+
+	if meta.WasDeleted(cfg) {
+		r.stop(req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	if r.isRunning(req.NamespacedName, cfg.GetGeneration()) &&
+		cfg.Status.ObservedGeneration == cfg.GetGeneration() {
+		l.Info("controller already running", "generation", cfg.GetGeneration())
+		return reconcile.Result{}, nil
+	}
+	cfg.Status.ObservedGeneration = cfg.GetGeneration()
+
+	// a syntax or parse error will not resolve itself, so we dont requeue
+	// the previous controller, if any, keeps on running
+	ceCtx, gvks, reason, result := parse(cfg)
+	if len(result) != 0 {
+		for _, res := range result {
+			l.Info("ccsyntax parsing failed", "reason", reason, "result", res)
+		}
+		return reconcile.Result{}, r.parseFailed(ctx, cfg, reason, result)
+	}
+	cfg.Status.ParseErrors = nil
+	cfg.Status.Resources = getResourcesStatus(cfg)
+	cfg.Status.Pipelines = getPipelinesStatus(ceCtx)
+	cfg.SetCondition(ctrlcfgv1.ConditionTypeParsed, metav1.ConditionTrue, ctrlcfgv1.ConditionReasonParseSucceeded, "")
+
+	// the resources might not be known yet in the api server, so we requeue
+	if err := validateMapping(r.mgr.GetRESTMapper(), gvks); err != nil {
+		l.Error(err, errResolveMapping)
+		if err := r.updateStatus(ctx, cfg, ctrlcfgv1.ConditionReasonUnresolvedResources, err.Error()); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, errors.Wrap(err, errResolveMapping)
+	}
+
+	if !r.isRunning(req.NamespacedName, cfg.GetGeneration()) {
+		// the watch pipelines of the controller run with the context of
+		// the controller
+		ctrlCtx, cancel := context.WithCancel(r.ctx)
+		c, err := r.build(ctrlCtx, cfg, ceCtx)
+		if err != nil {
+			cancel()
+			l.Error(err, errBuildCtrl)
+			if err := r.updateStatus(ctx, cfg, ctrlcfgv1.ConditionReasonBuildFailed, err.Error()); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, errors.Wrap(err, errBuildCtrl)
+		}
+		r.start(ctrlCtx, cancel, req.NamespacedName, cfg.GetGeneration(), c)
+	}
+
+	l.Info("reconcile finished, controller started", "generation", cfg.GetGeneration())
+	return reconcile.Result{}, r.updateStatus(ctx, cfg, "", "")
+}
+
+// build builds the lcnc controller of the controller config
+func (r *ctrlcfgReconciler) build(ctx context.Context, cfg *ctrlcfgv1.ControllerConfig, ceCtx ccsyntax.ConfigExecutionContext) (controller.Controller, error) {
+	noPrune, err := cfg.GetNoPruneGvks()
+	if err != nil {
+		return nil, err
+	}
+	return builder.New(&builder.Config{
+		Ctx:          ctx,
+		Mgr:          r.mgr,
+		CeCtx:        ceCtx,
+		GenericEvent: make(chan event.GenericEvent),
+		Unmanaged:    true,
+		MaxWorkers:   r.maxWorkers,
+		AllowWasm:    r.allowWasm,
+	}, controller.Options{
+		MaxConcurrentReconciles: r.concurrency,
+	}).Build(reconciler.New(&reconciler.Config{
+		Client:       r.client,
+		PollInterval: r.getPollInterval(cfg),
+		CeCtx:        ceCtx,
+		Cache:        r.mgr.GetCache(),
+		MaxWorkers:   r.maxWorkers,
+		AllowWasm:    r.allowWasm,
+		PruneDryRun:  cfg.IsPruneDryRun(),
+		NoPruneGVKs:  noPrune,
+		FieldManager: cfg.GetName(),
+		Recorder:     lcncevent.NewAPIRecorder(r.mgr.GetEventRecorderFor(cfg.GetName())),
+	}))
+}
+
+// parseFailed records the parse errors in the status of the controller config
+func (r *ctrlcfgReconciler) parseFailed(ctx context.Context, cfg *ctrlcfgv1.ControllerConfig, reason ctrlcfgv1.ConditionReason, result []ccsyntax.Result) error {
+	cfg.Status.ParseErrors = getParseErrors(result)
+	cfg.Status.Resources = nil
+	cfg.Status.Pipelines = nil
+	msg := fmt.Sprintf("%d error(s) found", len(result))
+	cfg.SetCondition(ctrlcfgv1.ConditionTypeParsed, metav1.ConditionFalse, reason, msg)
+	return r.updateStatus(ctx, cfg, ctrlcfgv1.ConditionReasonUnavailable, msg)
+}
+
+// updateStatus sets the running and ready conditions based on the controller
+// that is running for the controller config and updates the status.
+// The reason and msg explain why the latest generation is not running.
+func (r *ctrlcfgReconciler) updateStatus(ctx context.Context, cfg *ctrlcfgv1.ControllerConfig, reason ctrlcfgv1.ConditionReason, msg string) error {
+	generation, ok := r.getRunningGeneration(types.NamespacedName{Namespace: cfg.GetNamespace(), Name: cfg.GetName()})
+	switch {
+	case !ok:
+		cfg.SetCondition(ctrlcfgv1.ConditionTypeRunning, metav1.ConditionFalse, reason, msg)
+	case generation == cfg.GetGeneration():
+		cfg.SetCondition(ctrlcfgv1.ConditionTypeRunning, metav1.ConditionTrue, ctrlcfgv1.ConditionReasonStarted, "")
+	default:
+		cfg.SetCondition(ctrlcfgv1.ConditionTypeRunning, metav1.ConditionTrue, ctrlcfgv1.ConditionReasonOutdated,
+			fmt.Sprintf("running generation %d, %s", generation, msg))
+	}
+
+	if cfg.IsConditionTrue(ctrlcfgv1.ConditionTypeParsed) && ok && generation == cfg.GetGeneration() {
+		cfg.SetCondition(ctrlcfgv1.ConditionTypeReady, metav1.ConditionTrue, ctrlcfgv1.ConditionReasonAvailable, "")
+	} else {
+		cfg.SetCondition(ctrlcfgv1.ConditionTypeReady, metav1.ConditionFalse, ctrlcfgv1.ConditionReasonUnavailable, msg)
+	}
+
+	if err := r.client.Status().Update(ctx, cfg); err != nil {
+		r.l.Error(err, errUpdateStatus)
+		return errors.Wrap(err, errUpdateStatus)
+	}
+	return nil
+}
+
+// getPollInterval returns the poll interval of the controller config or the
+// default poll interval when the controller config does not override it
+func (r *ctrlcfgReconciler) getPollInterval(cfg *ctrlcfgv1.ControllerConfig) time.Duration {
+	if cfg.Spec.PollInterval != nil {
+		return cfg.Spec.PollInterval.Duration
+	}
+	return r.pollInterval
+}
+
+func (r *ctrlcfgReconciler) isRunning(nsn types.NamespacedName, generation int64) bool {
+	g, ok := r.getRunningGeneration(nsn)
+	return ok && g == generation
+}
+
+func (r *ctrlcfgReconciler) getRunningGeneration(nsn types.NamespacedName) (int64, bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	rc, ok := r.controllers[nsn]
+	if !ok {
+		return 0, false
+	}
+	return rc.generation, true
+}
+
+// start stops the controller that runs for a previous generation of the
+// controller config and starts the newly built controller with the supplied
+// context, the cancel func stops the controller
+func (r *ctrlcfgReconciler) start(ctx context.Context, cancel context.CancelFunc, nsn types.NamespacedName, generation int64, c controller.Controller) {
+	r.stop(nsn)
+
+	rc := &runningController{
+		generation: generation,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	r.m.Lock()
+	r.controllers[nsn] = rc
+	r.m.Unlock()
+
+	go func() {
+		defer close(rc.done)
+		if err := c.Start(ctx); err != nil {
+			r.l.Error(err, "controller stopped with error", "controllerConfig", nsn.String())
+			// the controller is rebuilt by the reconcile of the requeued
+			// controller config, unless it got stopped or replaced already
+			if r.remove(nsn, rc) {
+				r.requeue(nsn)
+			}
+		}
+	}()
+}
+
+// remove deletes the controller from the running controllers when it is
+// still the controller that runs for the controller config
+func (r *ctrlcfgReconciler) remove(nsn types.NamespacedName, rc *runningController) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if cur, ok := r.controllers[nsn]; !ok || cur != rc {
+		return false
+	}
+	delete(r.controllers, nsn)
+	rc.cancel()
+	return true
+}
+
+// requeue triggers a reconcile of the controller config
+func (r *ctrlcfgReconciler) requeue(nsn types.NamespacedName) {
+	cfg := &ctrlcfgv1.ControllerConfig{}
+	cfg.SetNamespace(nsn.Namespace)
+	cfg.SetName(nsn.Name)
+	select {
+	case r.ge <- event.GenericEvent{Object: cfg}:
+	case <-r.ctx.Done():
+	}
+}
+
+// stop cancels the controller and waits till the workers have finished
+func (r *ctrlcfgReconciler) stop(nsn types.NamespacedName) {
+	r.m.Lock()
+	rc, ok := r.controllers[nsn]
+	delete(r.controllers, nsn)
+	r.m.Unlock()
+	if !ok {
+		return
+	}
+	r.l.Info("stop controller", "controllerConfig", nsn.String(), "generation", rc.generation)
+	rc.cancel()
+	<-rc.done
+}
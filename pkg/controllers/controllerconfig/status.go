@@ -0,0 +1,103 @@
+package controllerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func getParseErrors(result []ccsyntax.Result) []ctrlcfgv1.ParseError {
+	pes := make([]ctrlcfgv1.ParseError, 0, len(result))
+	for _, res := range result {
+		pe := ctrlcfgv1.ParseError{
+			Message: res.Error,
+		}
+		if oc := res.OriginContext; oc != nil {
+			pe.FOW = string(oc.FOWS)
+			pe.Operation = string(oc.Operation)
+			pe.Pipeline = oc.Pipeline
+			pe.BlockVertexName = oc.BlockVertexName
+			pe.VertexName = oc.VertexName
+			pe.LocalVarName = oc.LocalVarName
+			if oc.GVK != nil {
+				pe.Resource = oc.GVK.String()
+			}
+		}
+		pes = append(pes, pe)
+	}
+	return pes
+}
+
+func getResourcesStatus(cfg *ctrlcfgv1.ControllerConfig) *ctrlcfgv1.ResourcesStatus {
+	// the gvks are validated by the parser, errors cannot occur at this stage
+	forGvks, _ := cfg.GetForGvk()
+	ownGvks, _ := cfg.GetOwnGvks()
+	watchGvks, _ := cfg.GetWatchGvks()
+	return &ctrlcfgv1.ResourcesStatus{
+		For:   getGvkList(forGvks),
+		Own:   getGvkList(ownGvks),
+		Watch: getGvkList(watchGvks),
+	}
+}
+
+func getGvkList(gvks []*schema.GroupVersionKind) []metav1.GroupVersionKind {
+	if len(gvks) == 0 {
+		return nil
+	}
+	l := make([]metav1.GroupVersionKind, 0, len(gvks))
+	for _, gvk := range gvks {
+		l = append(l, metav1.GroupVersionKind(*gvk))
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return getGvkString(l[i]) < getGvkString(l[j])
+	})
+	return l
+}
+
+func getGvkString(gvk metav1.GroupVersionKind) string {
+	return fmt.Sprintf("%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind)
+}
+
+func getPipelinesStatus(ceCtx ccsyntax.ConfigExecutionContext) []ctrlcfgv1.PipelineStatus {
+	pss := []ctrlcfgv1.PipelineStatus{}
+	for _, fow := range []ccsyntax.FOWS{ccsyntax.FOWFor, ccsyntax.FOWWatch} {
+		for gvk, od := range ceCtx.GetFOW(fow) {
+			for op, dctx := range od {
+				// no pipeline was configured for this operation
+				if dctx.PipelineName == "" {
+					continue
+				}
+				ps := ctrlcfgv1.PipelineStatus{
+					Name:      dctx.PipelineName,
+					FOW:       string(fow),
+					Resource:  metav1.GroupVersionKind(gvk),
+					Operation: string(op),
+					Vertices:  len(dctx.DAG.GetVertices()),
+				}
+				dctx.WalkBlockDAGs(func(blockPath []string, d rtdag.RuntimeDAG) {
+					if ps.BlockVertices == nil {
+						ps.BlockVertices = map[string]int{}
+					}
+					ps.BlockVertices[strings.Join(blockPath, ".")] = len(d.GetVertices())
+				})
+				pss = append(pss, ps)
+			}
+		}
+	}
+	sort.SliceStable(pss, func(i, j int) bool {
+		if pss[i].FOW != pss[j].FOW {
+			return pss[i].FOW < pss[j].FOW
+		}
+		if pss[i].Resource != pss[j].Resource {
+			return getGvkString(pss[i].Resource) < getGvkString(pss[j].Resource)
+		}
+		return pss[i].Operation < pss[j].Operation
+	})
+	return pss
+}
@@ -0,0 +1,80 @@
+package controllerconfig
+
+import (
+	"context"
+	"fmt"
+
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/manager"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// ValidatingWebhookPath is the path the validating webhook of the
+	// ControllerConfig is served at
+	ValidatingWebhookPath = "/validate-lcnc-yndd-io-v1-controllerconfig"
+)
+
+// SetupWebhook registers the validating webhook of the ControllerConfig
+// with the webhook server of the manager
+func SetupWebhook(mgr manager.Manager) {
+	mgr.GetWebhookServer().Register(ValidatingWebhookPath, admission.WithCustomValidator(
+		&ctrlcfgv1.ControllerConfig{},
+		&validator{mapper: mgr.GetRESTMapper()},
+	))
+}
+
+// +kubebuilder:webhook:path=/validate-lcnc-yndd-io-v1-controllerconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=lcnc.yndd.io,resources=controllerconfigs,verbs=create;update,versions=v1,name=vcontrollerconfig.lcnc.yndd.io,admissionReviewVersions=v1
+
+type validator struct {
+	mapper meta.RESTMapper
+}
+
+func (r *validator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return r.validate(obj)
+}
+
+func (r *validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return r.validate(newObj)
+}
+
+func (r *validator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (r *validator) validate(obj runtime.Object) error {
+	cfg, ok := obj.(*ctrlcfgv1.ControllerConfig)
+	if !ok {
+		return fmt.Errorf("expected a ControllerConfig, got: %T", obj)
+	}
+	_, gvks, _, result := parse(cfg)
+	if len(result) != 0 {
+		errs := make(field.ErrorList, 0, len(result))
+		for _, res := range result {
+			errs = append(errs, newFieldError(res.FieldPath(), res.Error))
+		}
+		return apierrors.NewInvalid(ctrlcfgv1.ResourceContextGroupVersionKind.GroupKind(), cfg.GetName(), errs)
+	}
+
+	if err := validateMapping(r.mapper, gvks); err != nil {
+		return apierrors.NewInvalid(ctrlcfgv1.ResourceContextGroupVersionKind.GroupKind(), cfg.GetName(), field.ErrorList{
+			newFieldError(field.NewPath("spec", "properties"), err.Error()),
+		})
+	}
+	return nil
+}
+
+// newFieldError returns an invalid field error without rendering the value
+// of the field, the detail holds the parser error
+func newFieldError(fp *field.Path, detail string) *field.Error {
+	return &field.Error{
+		Type:     field.ErrorTypeInvalid,
+		Field:    fp.String(),
+		BadValue: field.OmitValueType{},
+		Detail:   detail,
+	}
+}
@@ -0,0 +1,123 @@
+package controllerconfig
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const validConfig = `
+apiVersion: lcnc.yndd.io/v1
+kind: ControllerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  properties:
+    for:
+      topo:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Definition
+        applyPipelineRef: apply
+        deletePipelineRef: delete
+    pipelines:
+    - name: delete
+    - name: apply
+`
+
+const invalidConfig = `
+apiVersion: lcnc.yndd.io/v1
+kind: ControllerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  properties:
+    for:
+      topo:
+        resource:
+          apiVersion: topo.yndd.io/v1alpha1
+          kind: Definition
+        applyPipelineRef: apply
+        deletePipelineRef: delete
+    pipelines:
+    - name: delete
+    - name: apply
+      tasks:
+        badTask:
+          type: slice
+          input: {}
+`
+
+func newControllerConfig(s string) *ctrlcfgv1.ControllerConfig {
+	cfg := &ctrlcfgv1.ControllerConfig{}
+	Expect(yaml.Unmarshal([]byte(s), cfg)).To(Succeed())
+	return cfg
+}
+
+func getCauses(err error) []metav1.StatusCause {
+	status, ok := err.(apierrors.APIStatus)
+	Expect(ok).To(BeTrue())
+	Expect(status.Status().Details).NotTo(BeNil())
+	return status.Status().Details.Causes
+}
+
+var _ = Describe("Webhook", func() {
+	var v *validator
+
+	BeforeEach(func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(schema.GroupVersionKind{Group: "topo.yndd.io", Version: "v1alpha1", Kind: "Definition"}, meta.RESTScopeNamespace)
+		v = &validator{mapper: mapper}
+	})
+
+	Describe("ValidateCreate", func() {
+		It("should accept a valid controller config", func() {
+			Expect(v.ValidateCreate(context.TODO(), newControllerConfig(validConfig))).To(Succeed())
+		})
+
+		It("should reject an object that is not a controller config", func() {
+			Expect(v.ValidateCreate(context.TODO(), &metav1.Status{})).NotTo(Succeed())
+		})
+
+		It("should reject a controller config with a syntax error in a task", func() {
+			err := v.ValidateCreate(context.TODO(), newControllerConfig(invalidConfig))
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(getCauses(err)).To(ConsistOf(metav1.StatusCause{
+				Type:    metav1.CauseTypeFieldValueInvalid,
+				Field:   "spec.properties.pipelines[1].tasks.badTask",
+				Message: "Invalid value: value needs to be present in slice",
+			}))
+		})
+
+		It("should reject a controller config with a resource unknown to the api server", func() {
+			v.mapper = meta.NewDefaultRESTMapper(nil)
+			err := v.ValidateCreate(context.TODO(), newControllerConfig(validConfig))
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			causes := getCauses(err)
+			Expect(causes).To(HaveLen(1))
+			Expect(causes[0].Field).To(Equal("spec.properties"))
+		})
+	})
+
+	Describe("ValidateUpdate", func() {
+		It("should validate the new controller config", func() {
+			err := v.ValidateUpdate(context.TODO(), newControllerConfig(validConfig), newControllerConfig(invalidConfig))
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	Describe("ValidateDelete", func() {
+		It("should accept the delete of an invalid controller config", func() {
+			Expect(v.ValidateDelete(context.TODO(), newControllerConfig(invalidConfig))).To(Succeed())
+		})
+	})
+})
@@ -18,29 +18,44 @@ package eventhandler
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/yndd/lcnc-runtime/pkg/exec/builder"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
 	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+	"github.com/yndd/lcnc-runtime/pkg/exec/service"
 	"github.com/yndd/lcnc-runtime/pkg/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 type Config struct {
+	// Ctx is the context the watch pipeline runs with, it is cancelled
+	// when the controller stops
+	Ctx            context.Context
 	Client         client.Client
+	Cache          client.Reader
 	RootVertexName string
 	GVK            *schema.GroupVersionKind
-	DAG            rtdag.RuntimeDAG
+	// ForGVK is the gvk of the for resources the watch pipeline enqueues
+	ForGVK   *schema.GroupVersionKind
+	DAG      rtdag.RuntimeDAG
+	Services service.Services
+	// MaxWorkers is the maximum number of functions that run concurrently
+	MaxWorkers int
+	// AllowWasm enables wasm functions in the watch pipeline
+	AllowWasm bool
 }
 
 func New(c *Config) handler.EventHandler {
@@ -49,22 +64,37 @@ func New(c *Config) handler.EventHandler {
 	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	return &eventhandler{
-		//ctx:    ctx,
+		ctx:            ctx,
 		client:         c.Client,
+		cache:          c.Cache,
 		rootVertexName: c.RootVertexName,
 		gvk:            c.GVK,
+		forGVK:         c.ForGVK,
 		d:              c.DAG,
+		services:       c.Services,
+		maxWorkers:     c.MaxWorkers,
+		allowWasm:      c.AllowWasm,
 		l:              ctrl.Log.WithName("lcnc eventhandler"),
 	}
 }
 
 type eventhandler struct {
-	client client.Client
-	//ctx    context.Context
+	client         client.Client
+	cache          client.Reader
+	ctx            context.Context
 	rootVertexName string
 	gvk            *schema.GroupVersionKind
+	forGVK         *schema.GroupVersionKind
 	d              rtdag.RuntimeDAG
+	services       service.Services
+	maxWorkers     int
+	allowWasm      bool
 
 	l logr.Logger
 }
@@ -103,6 +133,15 @@ func (r *eventhandler) add(obj runtime.Object, queue adder) {
 		return
 	}
 
+	sc, err := service.GetClients(r.services)
+	if err != nil {
+		r.l.Error(err, "cannot get svc clients")
+		return
+	}
+	for _, c := range sc {
+		defer c.Close()
+	}
+
 	namespace := u.GetNamespace()
 	if u.GetNamespace() == "" {
 		namespace = "default"
@@ -111,32 +150,71 @@ func (r *eventhandler) add(obj runtime.Object, queue adder) {
 	o := output.New()
 	result := result.New()
 	e := builder.New(&builder.Config{
-		Name:      u.GetName(),
-		Namespace: namespace,
-		Data:      x,
-		Client:    r.client,
-		GVK:       r.gvk,
-		DAG:       r.d,
-		Output:    o,
-		Result:    result,
+		Name:           u.GetName(),
+		Namespace:      namespace,
+		Data:           x,
+		Client:         r.client,
+		Cache:          r.cache,
+		GVK:            r.gvk,
+		DAG:            r.d,
+		Output:         o,
+		Result:         result,
+		ServiceClients: sc,
+		MaxWorkers:     r.maxWorkers,
+		AllowWasm:      r.allowWasm,
 	})
 
-	e.Run(context.TODO())
+	e.Run(r.ctx)
 	//o.Print()
 	result.Print()
 
-	// for all the output add the queues
-
-	/*
-		queue.Add(reconcile.Request{NamespacedName: types.NamespacedName{
-			Namespace: toponode.GetNamespace(),
-			Name:      toponode.GetName()}},
-		)
-	*/
+	// the final output of the watch pipeline are the for resources that
+	// are affected by the event
+	for _, req := range r.getRequests(o) {
+		r.l.Info("watch event enqueue", "request", req.String())
+		queue.Add(req)
+	}
 
 	r.l.Info("watch event finsihed...")
 }
 
+// getRequests returns a request for each for resource in the final output,
+// the items of the output need a name and the items with a gvk other than
+// the gvk of the for resource are ignored
+func (r *eventhandler) getRequests(o output.Output) []reconcile.Request {
+	reqs := []reconcile.Request{}
+	seen := map[types.NamespacedName]struct{}{}
+	for _, oi := range o.GetFinalOutputInfo() {
+		d, ok := oi.Data.([]any)
+		if !ok {
+			continue
+		}
+		for _, v := range d {
+			item, ok := v.(map[string]any)
+			if !ok {
+				r.l.Info("watch output is not an object", "type", fmt.Sprintf("%T", v))
+				continue
+			}
+			ref := &unstructured.Unstructured{Object: item}
+			if ref.GetKind() != "" && ref.GroupVersionKind() != *r.forGVK {
+				r.l.Info("watch output is not a for resource", "gvk", ref.GroupVersionKind().String())
+				continue
+			}
+			nsn := types.NamespacedName{Namespace: ref.GetNamespace(), Name: ref.GetName()}
+			if nsn.Name == "" {
+				r.l.Info("watch output has no name")
+				continue
+			}
+			if _, ok := seen[nsn]; ok {
+				continue
+			}
+			seen[nsn] = struct{}{}
+			reqs = append(reqs, reconcile.Request{NamespacedName: nsn})
+		}
+	}
+	return reqs
+}
+
 type adder interface {
 	Add(item interface{})
 }
@@ -0,0 +1,13 @@
+package eventhandler
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEventhandler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Eventhandler Suite")
+}
@@ -0,0 +1,87 @@
+package eventhandler
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/exec/output"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newRequest(namespace, name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+var _ = Describe("Eventhandler", func() {
+	Describe("getRequests", func() {
+		var (
+			r *eventhandler
+			o output.Output
+		)
+
+		BeforeEach(func() {
+			r = &eventhandler{
+				forGVK: &schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "App"},
+				l:      logr.Discard(),
+			}
+			o = output.New()
+		})
+
+		It("should return a request for each for resource", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{
+				map[string]any{"apiVersion": "example.com/v1", "kind": "App", "metadata": map[string]any{"namespace": "default", "name": "a"}},
+				map[string]any{"apiVersion": "example.com/v1", "kind": "App", "metadata": map[string]any{"name": "b"}},
+			}})
+			Expect(r.getRequests(o)).To(Equal([]reconcile.Request{
+				newRequest("default", "a"),
+				newRequest("", "b"),
+			}))
+		})
+
+		It("should return a request for an item without gvk", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{
+				map[string]any{"metadata": map[string]any{"namespace": "default", "name": "a"}},
+			}})
+			Expect(r.getRequests(o)).To(Equal([]reconcile.Request{newRequest("default", "a")}))
+		})
+
+		It("should ignore the resources of another gvk", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{
+				map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"namespace": "default", "name": "a"}},
+			}})
+			Expect(r.getRequests(o)).To(BeEmpty())
+		})
+
+		It("should ignore the items without a name", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{
+				map[string]any{"metadata": map[string]any{"namespace": "default"}},
+			}})
+			Expect(r.getRequests(o)).To(BeEmpty())
+		})
+
+		It("should ignore the items that are not an object", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{"a", 1}})
+			Expect(r.getRequests(o)).To(BeEmpty())
+		})
+
+		It("should ignore the internal outputs", func() {
+			o.AddEntry("apps", &output.OutputInfo{Internal: true, Data: []any{
+				map[string]any{"metadata": map[string]any{"namespace": "default", "name": "a"}},
+			}})
+			Expect(r.getRequests(o)).To(BeEmpty())
+		})
+
+		It("should return a request once", func() {
+			o.AddEntry("apps", &output.OutputInfo{Data: []any{
+				map[string]any{"metadata": map[string]any{"namespace": "default", "name": "a"}},
+				map[string]any{"metadata": map[string]any{"namespace": "default", "name": "a"}},
+			}})
+			o.AddEntry("more", &output.OutputInfo{Data: []any{
+				map[string]any{"metadata": map[string]any{"namespace": "default", "name": "a"}},
+			}})
+			Expect(r.getRequests(o)).To(Equal([]reconcile.Request{newRequest("default", "a")}))
+		})
+	})
+})
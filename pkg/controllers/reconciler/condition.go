@@ -0,0 +1,111 @@
+package reconciler
+
+import (
+	"fmt"
+
+	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// conditionType of the for resource
+type conditionType string
+
+const (
+	// conditionTypeSynced indicates the pipeline of the for resource ran
+	// and its resources were applied
+	conditionTypeSynced conditionType = "Synced"
+	// conditionTypeReady indicates the resources of the for resource are
+	// applied as the pipeline defines them
+	conditionTypeReady conditionType = "Ready"
+)
+
+// conditionReason of the for resource
+type conditionReason string
+
+const (
+	conditionReasonReconcileSuccess conditionReason = "ReconcileSuccess"
+	conditionReasonReconcileError   conditionReason = "ReconcileError"
+	conditionReasonPipelineFailed   conditionReason = "PipelineFailed"
+	conditionReasonApplyConflict    conditionReason = "ApplyConflict"
+	conditionReasonAvailable        conditionReason = "Available"
+	conditionReasonUnavailable      conditionReason = "Unavailable"
+)
+
+// setCondition sets the condition in the status of the resource, the
+// transition time only changes when the status changes
+func setCondition(cr *unstructured.Unstructured, t conditionType, status metav1.ConditionStatus, reason conditionReason, msg string) error {
+	conditions := []metav1.Condition{}
+	l, _, _ := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	for _, v := range l {
+		m, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		// conditions that cannot be converted are dropped
+		c := metav1.Condition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err != nil {
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	apimeta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               string(t),
+		Status:             status,
+		ObservedGeneration: cr.GetGeneration(),
+		Reason:             string(reason),
+		Message:            msg,
+	})
+	l = make([]any, 0, len(conditions))
+	for _, c := range conditions {
+		c := c
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&c)
+		if err != nil {
+			return err
+		}
+		l = append(l, m)
+	}
+	return unstructured.SetNestedSlice(cr.Object, l, "status", "conditions")
+}
+
+// setReconcileError reports an error of the reconcile in the conditions of
+// the resource
+func setReconcileError(cr *unstructured.Unstructured, err error) error {
+	if err := setCondition(cr, conditionTypeSynced, metav1.ConditionFalse, conditionReasonReconcileError, err.Error()); err != nil {
+		return err
+	}
+	return setCondition(cr, conditionTypeReady, metav1.ConditionFalse, conditionReasonUnavailable, "")
+}
+
+// setPipelineResult reports the result of the pipeline and the apply
+// conflicts in the conditions of the resource, a failed pipeline is reported
+// with the vertex that failed
+func setPipelineResult(cr *unstructured.Unstructured, res result.Result, conflicts int) error {
+	if !res.Success() {
+		msg := getPipelineFailure(res)
+		if err := setCondition(cr, conditionTypeSynced, metav1.ConditionFalse, conditionReasonPipelineFailed, msg); err != nil {
+			return err
+		}
+		return setCondition(cr, conditionTypeReady, metav1.ConditionFalse, conditionReasonPipelineFailed, msg)
+	}
+	if err := setCondition(cr, conditionTypeSynced, metav1.ConditionTrue, conditionReasonReconcileSuccess, ""); err != nil {
+		return err
+	}
+	if conflicts != 0 {
+		msg := fmt.Sprintf("%d resources have apply conflicts", conflicts)
+		return setCondition(cr, conditionTypeReady, metav1.ConditionFalse, conditionReasonApplyConflict, msg)
+	}
+	return setCondition(cr, conditionTypeReady, metav1.ConditionTrue, conditionReasonAvailable, "")
+}
+
+// getPipelineFailure returns the vertex that failed in the pipeline and the
+// reason it failed
+func getPipelineFailure(res result.Result) string {
+	ri := res.Failure()
+	if ri == nil {
+		return "pipeline failed"
+	}
+	return fmt.Sprintf("vertex %s failed: %s", ri.VertexName, ri.Reason)
+}
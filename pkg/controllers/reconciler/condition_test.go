@@ -0,0 +1,123 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/applicator"
+	"github.com/yndd/lcnc-runtime/pkg/event"
+	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// getConditions returns the conditions in the status of the resource
+func getConditions(cr *unstructured.Unstructured) []metav1.Condition {
+	l, _, err := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	Expect(err).NotTo(HaveOccurred())
+	conditions := []metav1.Condition{}
+	for _, v := range l {
+		c := metav1.Condition{}
+		Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(v.(map[string]any), &c)).To(Succeed())
+		conditions = append(conditions, c)
+	}
+	return conditions
+}
+
+func newPipelineResult(success bool, failures ...*result.ResultInfo) result.Result {
+	res := result.New()
+	for _, ri := range failures {
+		res.Add(ri)
+	}
+	res.Add(&result.ResultInfo{Type: result.ExecRootType, ExecName: "root", VertexName: "total", Success: success})
+	return res
+}
+
+var _ = Describe("Reconciler", func() {
+	Describe("setPipelineResult", func() {
+		var cr *unstructured.Unstructured
+
+		BeforeEach(func() {
+			cr = newResource("example.com/v1", "App", "default", "owner")
+			cr.SetGeneration(2)
+		})
+
+		It("should report a successful pipeline", func() {
+			Expect(setPipelineResult(cr, newPipelineResult(true), 0)).To(Succeed())
+			conditions := getConditions(cr)
+			synced := apimeta.FindStatusCondition(conditions, string(conditionTypeSynced))
+			Expect(synced.Status).To(Equal(metav1.ConditionTrue))
+			Expect(synced.ObservedGeneration).To(Equal(int64(2)))
+			ready := apimeta.FindStatusCondition(conditions, string(conditionTypeReady))
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			Expect(ready.Reason).To(Equal(string(conditionReasonAvailable)))
+		})
+
+		It("should report the apply conflicts", func() {
+			Expect(setPipelineResult(cr, newPipelineResult(true), 2)).To(Succeed())
+			conditions := getConditions(cr)
+			Expect(apimeta.IsStatusConditionTrue(conditions, string(conditionTypeSynced))).To(BeTrue())
+			ready := apimeta.FindStatusCondition(conditions, string(conditionTypeReady))
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(string(conditionReasonApplyConflict)))
+			Expect(ready.Message).To(Equal("2 resources have apply conflicts"))
+		})
+
+		It("should report the vertex of a failed pipeline", func() {
+			res := newPipelineResult(false, &result.ResultInfo{Type: result.ExecRootType, ExecName: "root", VertexName: "a", Reason: "boom"})
+			Expect(setPipelineResult(cr, res, 0)).To(Succeed())
+			for _, c := range getConditions(cr) {
+				Expect(c.Status).To(Equal(metav1.ConditionFalse))
+				Expect(c.Reason).To(Equal(string(conditionReasonPipelineFailed)))
+				Expect(c.Message).To(Equal("vertex a failed: boom"))
+			}
+		})
+
+		It("should keep the transition time when the status does not change", func() {
+			Expect(setPipelineResult(cr, newPipelineResult(true), 0)).To(Succeed())
+			t := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+			l := []any{}
+			for _, c := range getConditions(cr) {
+				c.LastTransitionTime = t
+				m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&c)
+				Expect(err).NotTo(HaveOccurred())
+				l = append(l, m)
+			}
+			Expect(unstructured.SetNestedSlice(cr.Object, l, "status", "conditions")).To(Succeed())
+
+			Expect(setPipelineResult(cr, newPipelineResult(true), 0)).To(Succeed())
+			for _, c := range getConditions(cr) {
+				Expect(c.LastTransitionTime.Equal(&t)).To(BeTrue())
+			}
+		})
+	})
+
+	Describe("reconcileFailed", func() {
+		It("should return the error when the status cannot be updated", func() {
+			// the resource does not exist so the status update fails
+			r := &reconciler{
+				client: applicator.ClientApplicator{Client: fake.NewClientBuilder().Build()},
+				record: &recorder{},
+				l:      logr.Discard(),
+			}
+			cr := newResource("v1", "ConfigMap", "default", "a")
+			res, err := r.reconcileFailed(context.Background(), cr, fmt.Errorf("boom"))
+			Expect(res).To(Equal(reconcile.Result{}))
+			Expect(err).To(MatchError("reconcile failed: boom"))
+			Expect(r.record.(*recorder).reasons).To(Equal([]event.Reason{reasonReconcileError}))
+
+			synced := apimeta.FindStatusCondition(getConditions(cr), string(conditionTypeSynced))
+			Expect(synced.Status).To(Equal(metav1.ConditionFalse))
+			Expect(synced.Reason).To(Equal(string(conditionReasonReconcileError)))
+			Expect(synced.Message).To(Equal("boom"))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	"time"
+
+	"github.com/yndd/lcnc-runtime/pkg/event"
+	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// eventInterval is the interval in which the same event is recorded
+	// only once for a resource
+	eventInterval = 5 * time.Minute
+	// annotations of the events
+	pipelineAnnotation  = "lcnc.yndd.io/pipeline"
+	operationAnnotation = "lcnc.yndd.io/operation"
+	vertexAnnotation    = "lcnc.yndd.io/vertex"
+)
+
+// reasons of the events
+const (
+	reasonReconcileError        event.Reason = "ReconcileError"
+	reasonPipelineStarted       event.Reason = "PipelineStarted"
+	reasonPipelineFailed        event.Reason = "PipelineFailed"
+	reasonVertexFailed          event.Reason = "VertexFailed"
+	reasonApplied               event.Reason = "Applied"
+	reasonApplyConflict         event.Reason = "ApplyConflict"
+	reasonDeleted               event.Reason = "Deleted"
+	reasonPruned                event.Reason = "Pruned"
+	reasonPruneDryRun           event.Reason = "PruneDryRun"
+	reasonCannotPrune           event.Reason = "CannotPrune"
+	reasonCannotRemoveFinalizer event.Reason = "CannotRemoveFinalizer"
+)
+
+// recordVertexFailures records a warning event for every vertex that failed
+// in the pipeline, the events are annotated with the name of the vertex
+func recordVertexFailures(record event.Recorder, obj runtime.Object, res result.Result) {
+	for _, ri := range res.Failures() {
+		record.WithAnnotations(vertexAnnotation, ri.VertexName).Event(obj, event.Event{
+			Type:    event.TypeWarning,
+			Reason:  reasonVertexFailed,
+			Message: "vertex " + ri.VertexName + " failed: " + ri.Reason,
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// inventoryAnnotation records the resources that were applied for a resource,
+// it is used to prune the resources that are no longer part of the output
+const inventoryAnnotation = "lcnc.yndd.io/inventory"
+
+// objectRef identifies a resource in the inventory
+type objectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func getObjectRef(u *unstructured.Unstructured) objectRef {
+	return objectRef{
+		APIVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+	}
+}
+
+func (r objectRef) GroupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind)
+}
+
+func (r objectRef) String() string {
+	return strings.Join([]string{r.APIVersion, r.Kind, r.Namespace, r.Name}, "/")
+}
+
+// getUnstructured returns an unstructured object that identifies the resource
+func (r objectRef) getUnstructured() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(r.APIVersion)
+	u.SetKind(r.Kind)
+	u.SetNamespace(r.Namespace)
+	u.SetName(r.Name)
+	return u
+}
+
+// inventory is the set of resources that were applied for a resource
+type inventory map[objectRef]struct{}
+
+// getInventory returns the inventory that is recorded in the annotation of
+// the resource
+func getInventory(cr *unstructured.Unstructured) (inventory, error) {
+	inv := inventory{}
+	v, ok := cr.GetAnnotations()[inventoryAnnotation]
+	if !ok {
+		return inv, nil
+	}
+	refs := []objectRef{}
+	if err := json.Unmarshal([]byte(v), &refs); err != nil {
+		return inv, err
+	}
+	for _, ref := range refs {
+		inv[ref] = struct{}{}
+	}
+	return inv, nil
+}
+
+func (r inventory) add(ref objectRef) {
+	r[ref] = struct{}{}
+}
+
+func (r inventory) has(ref objectRef) bool {
+	_, ok := r[ref]
+	return ok
+}
+
+func (r inventory) equal(o inventory) bool {
+	if len(r) != len(o) {
+		return false
+	}
+	for ref := range r {
+		if !o.has(ref) {
+			return false
+		}
+	}
+	return true
+}
+
+// marshal returns the value of the inventory annotation, the resources are
+// sorted to keep the annotation stable across reconciles
+func (r inventory) marshal() (string, error) {
+	refs := make([]objectRef, 0, len(r))
+	for ref := range r {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+	b, err := json.Marshal(refs)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,223 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/applicator"
+	"github.com/yndd/lcnc-runtime/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func configMapRef(name string) objectRef {
+	return objectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: name}
+}
+
+func newInventory(refs ...objectRef) inventory {
+	inv := inventory{}
+	for _, ref := range refs {
+		inv.add(ref)
+	}
+	return inv
+}
+
+// recorder records the reasons of the events
+type recorder struct {
+	reasons []event.Reason
+}
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) {
+	r.reasons = append(r.reasons, e.Reason)
+}
+
+func (r *recorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+// failingClient fails to delete the resources with the name fail
+type failingClient struct {
+	client.Client
+}
+
+func (c *failingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetName() == "fail" {
+		return fmt.Errorf("delete failed")
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// getConfigMapNames returns the names of the configmaps in the default namespace
+func getConfigMapNames(c client.Client) []string {
+	l := &unstructured.UnstructuredList{}
+	l.SetAPIVersion("v1")
+	l.SetKind("ConfigMapList")
+	Expect(c.List(context.Background(), l, client.InNamespace("default"))).To(Succeed())
+	names := []string{}
+	for _, o := range l.Items {
+		names = append(names, o.GetName())
+	}
+	return names
+}
+
+var _ = Describe("Reconciler", func() {
+	Describe("getInventory", func() {
+		It("should return an empty inventory without annotation", func() {
+			Expect(getInventory(&unstructured.Unstructured{})).To(Equal(inventory{}))
+		})
+
+		It("should return an empty inventory", func() {
+			cr := &unstructured.Unstructured{}
+			cr.SetAnnotations(map[string]string{inventoryAnnotation: "[]"})
+			Expect(getInventory(cr)).To(Equal(inventory{}))
+		})
+
+		It("should return the resources of the annotation", func() {
+			cr := &unstructured.Unstructured{}
+			cr.SetAnnotations(map[string]string{
+				inventoryAnnotation: `[{"apiVersion":"v1","kind":"ConfigMap","namespace":"default","name":"a"},{"apiVersion":"v1","kind":"ConfigMap","namespace":"default","name":"b"}]`,
+			})
+			Expect(getInventory(cr)).To(Equal(newInventory(configMapRef("a"), configMapRef("b"))))
+		})
+
+		It("should fail for an invalid annotation", func() {
+			cr := &unstructured.Unstructured{}
+			cr.SetAnnotations(map[string]string{inventoryAnnotation: "{"})
+			inv, err := getInventory(cr)
+			Expect(err).To(HaveOccurred())
+			Expect(inv).To(Equal(inventory{}))
+		})
+
+		It("should return the inventory that was marshaled", func() {
+			inv := newInventory(configMapRef("a"), objectRef{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "a"})
+			s, err := inv.marshal()
+			Expect(err).NotTo(HaveOccurred())
+			cr := &unstructured.Unstructured{}
+			cr.SetAnnotations(map[string]string{inventoryAnnotation: s})
+			Expect(getInventory(cr)).To(Equal(inv))
+		})
+	})
+
+	Describe("marshal", func() {
+		It("should marshal an empty inventory", func() {
+			Expect(inventory{}.marshal()).To(Equal("[]"))
+		})
+
+		It("should omit the namespace of a cluster scoped resource", func() {
+			inv := newInventory(objectRef{APIVersion: "v1", Kind: "Namespace", Name: "a"})
+			Expect(inv.marshal()).To(Equal(`[{"apiVersion":"v1","kind":"Namespace","name":"a"}]`))
+		})
+
+		It("should sort the resources", func() {
+			inv := newInventory(configMapRef("b"), configMapRef("a"))
+			Expect(inv.marshal()).To(Equal(`[{"apiVersion":"v1","kind":"ConfigMap","namespace":"default","name":"a"},{"apiVersion":"v1","kind":"ConfigMap","namespace":"default","name":"b"}]`))
+		})
+	})
+
+	Describe("equal", func() {
+		It("should be equal for empty inventories", func() {
+			Expect(inventory{}.equal(inventory{})).To(BeTrue())
+		})
+
+		It("should be equal for the same resources", func() {
+			a := newInventory(configMapRef("a"), configMapRef("b"))
+			b := newInventory(configMapRef("b"), configMapRef("a"))
+			Expect(a.equal(b)).To(BeTrue())
+			Expect(b.equal(a)).To(BeTrue())
+		})
+
+		It("should not be equal with an additional resource", func() {
+			a := newInventory(configMapRef("a"))
+			b := newInventory(configMapRef("a"), configMapRef("b"))
+			Expect(a.equal(b)).To(BeFalse())
+			Expect(b.equal(a)).To(BeFalse())
+		})
+
+		It("should not be equal for other resources", func() {
+			Expect(newInventory(configMapRef("a")).equal(newInventory(configMapRef("b")))).To(BeFalse())
+		})
+	})
+
+	Describe("prune", func() {
+		var (
+			c      client.Client
+			r      *reconciler
+			record *recorder
+		)
+
+		BeforeEach(func() {
+			c = fake.NewClientBuilder().WithObjects(
+				configMapRef("a").getUnstructured(),
+				configMapRef("b").getUnstructured(),
+			).Build()
+			r = &reconciler{
+				client:  applicator.ClientApplicator{Client: &failingClient{Client: c}},
+				noPrune: map[schema.GroupVersionKind]struct{}{},
+				l:       logr.Discard(),
+			}
+			record = &recorder{}
+		})
+
+		It("should not delete the resources of the inventory", func() {
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("a")), newInventory(configMapRef("a")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(newInventory(configMapRef("a"))))
+			Expect(record.reasons).To(BeEmpty())
+			Expect(getConfigMapNames(c)).To(ConsistOf("a", "b"))
+		})
+
+		It("should delete a resource that is no longer applied", func() {
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("a"), configMapRef("b")), newInventory(configMapRef("a")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(newInventory(configMapRef("a"))))
+			Expect(record.reasons).To(Equal([]event.Reason{reasonPruned}))
+			Expect(getConfigMapNames(c)).To(ConsistOf("a"))
+		})
+
+		It("should delete all resources for an empty inventory", func() {
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("a"), configMapRef("b")), inventory{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(inventory{}))
+			Expect(record.reasons).To(Equal([]event.Reason{reasonPruned, reasonPruned}))
+			Expect(getConfigMapNames(c)).To(BeEmpty())
+		})
+
+		It("should ignore a resource that no longer exists", func() {
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("c")), inventory{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(inventory{}))
+			Expect(record.reasons).To(Equal([]event.Reason{reasonPruned}))
+			Expect(getConfigMapNames(c)).To(ConsistOf("a", "b"))
+		})
+
+		It("should keep the resource in the inventory for a dry run", func() {
+			r.pruneDryRun = true
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("a"), configMapRef("b")), newInventory(configMapRef("a")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(newInventory(configMapRef("a"), configMapRef("b"))))
+			Expect(record.reasons).To(Equal([]event.Reason{reasonPruneDryRun}))
+			Expect(getConfigMapNames(c)).To(ConsistOf("a", "b"))
+		})
+
+		It("should not delete the resources of a gvk that is not pruned", func() {
+			r.noPrune[schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}] = struct{}{}
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("a"), configMapRef("b")), inventory{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inv).To(Equal(inventory{}))
+			Expect(record.reasons).To(BeEmpty())
+			Expect(getConfigMapNames(c)).To(ConsistOf("a", "b"))
+		})
+
+		It("should keep a resource that cannot be deleted in the inventory", func() {
+			inv, err := r.prune(context.Background(), record, &unstructured.Unstructured{}, newInventory(configMapRef("fail"), configMapRef("b")), inventory{})
+			Expect(err).To(MatchError(ContainSubstring("cannot prune resource")))
+			Expect(inv).To(Equal(newInventory(configMapRef("fail"))))
+			Expect(record.reasons).To(ConsistOf(reasonCannotPrune, reasonPruned))
+			Expect(getConfigMapNames(c)).To(ConsistOf("a"))
+		})
+	})
+})
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -26,26 +29,54 @@ import (
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	"github.com/yndd/lcnc-runtime/pkg/exec/service"
 	"github.com/yndd/lcnc-runtime/pkg/meta"
 )
 
 const (
 	// const
 	defaultFinalizerName = "lcnc.yndd.io/finalizer"
+	defaultFieldManager  = "lcnc-runtime"
+	// pollIntervalAnnotation overrides the poll interval of a resource
+	pollIntervalAnnotation = "lcnc.yndd.io/poll-interval"
+	// pollJitter is the maximum factor of the poll interval that is added
+	// to spread the polls of the resources
+	pollJitter = 0.1
 	// errors
 	errGetCr        = "cannot get resource"
 	errUpdateStatus = "cannot update resource status"
 	errMarshalCr    = "cannot marshal resource"
-
-// reconcileFailed = "reconcile failed"
-
+	errPrune        = "cannot prune resources"
+	errPipeline     = "pipeline failed"
+	errConditions   = "cannot set conditions"
+	errReconcile    = "reconcile failed"
 )
 
 type Config struct {
-	Client       client.Client
+	Client client.Client
+	// PollInterval is the interval after which a resource is reconciled
+	// again to correct drift, a zero interval disables polling
 	PollInterval time.Duration
 	CeCtx        ccsyntax.ConfigExecutionContext
 	FnMap        fnmap.FuncMap
+	// Cache is the informer cache the queries in the pipelines read from
+	Cache client.Reader
+	// MaxWorkers is the maximum number of functions that run concurrently
+	// in a pipeline
+	MaxWorkers int
+	// AllowWasm enables wasm functions in the pipelines
+	AllowWasm bool
+	// PruneDryRun logs the resources that would be pruned instead of
+	// deleting them
+	PruneDryRun bool
+	// NoPruneGVKs are the gvks of the resources that are not pruned
+	NoPruneGVKs []*schema.GroupVersionKind
+	// FieldManager is the field manager that applies the resources with
+	// server-side apply
+	FieldManager string
+	// Recorder records the events of the pipelines on the for resources,
+	// the same event is recorded at most once per event interval
+	Recorder event.Recorder
 }
 
 func New(c *Config) reconcile.Reconciler {
@@ -54,22 +85,49 @@ func New(c *Config) reconcile.Reconciler {
 	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	noPrune := make(map[schema.GroupVersionKind]struct{}, len(c.NoPruneGVKs))
+	for _, gvk := range c.NoPruneGVKs {
+		noPrune[*gvk] = struct{}{}
+	}
+
+	fieldManager := c.FieldManager
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	var record event.Recorder = event.NewNopRecorder()
+	if c.Recorder != nil {
+		record = event.NewRateLimitedRecorder(c.Recorder, eventInterval)
+	}
+
 	return &reconciler{
-		client:       applicator.ClientApplicator{Client: c.Client, Applicator: applicator.NewAPIPatchingApplicator(c.Client)},
+		client:       applicator.ClientApplicator{Client: c.Client, Applicator: applicator.NewAPIServerSideApplicator(c.Client, fieldManager, false)},
+		forceApply:   applicator.NewAPIServerSideApplicator(c.Client, fieldManager, true),
 		pollInterval: c.PollInterval,
 		ceCtx:        c.CeCtx,
 		fnMap:        c.FnMap,
+		cache:        c.Cache,
+		maxWorkers:   c.MaxWorkers,
+		allowWasm:    c.AllowWasm,
+		pruneDryRun:  c.PruneDryRun,
+		noPrune:      noPrune,
 		l:            ctrl.Log.WithName("lcnc reconcile"),
 		f:            meta.NewAPIFinalizer(c.Client, defaultFinalizerName),
-		record:       event.NewNopRecorder(),
+		record:       record,
 	}
 }
 
 type reconciler struct {
 	client       applicator.ClientApplicator
+	forceApply   applicator.Applicator
 	pollInterval time.Duration
 	ceCtx        ccsyntax.ConfigExecutionContext
 	fnMap        fnmap.FuncMap
+	cache        client.Reader
+	maxWorkers   int
+	allowWasm    bool
+	pruneDryRun  bool
+	noPrune      map[schema.GroupVersionKind]struct{}
 	f            meta.Finalizer
 	l            logr.Logger
 	record       event.Recorder
@@ -88,24 +146,21 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return reconcile.Result{}, errors.Wrap(meta.IgnoreNotFound(err), errGetCr)
 	}
 
-	//record := r.record.WithAnnotations()
-
 	x, err := meta.MarshalData(cr)
 	if err != nil {
 		r.l.Error(err, "cannot marshal data")
-		return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+		return r.reconcileFailed(ctx, cr, err)
 	}
 
 	if err := r.f.AddFinalizer(ctx, cr); err != nil {
 		r.l.Error(err, "cannot add finalizer")
-		//managed.SetConditions(nddv1.ReconcileError(err), nddv1.Unknown())
-		return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+		return r.reconcileFailed(ctx, cr, err)
 	}
 
 	sc, err := r.getSvcClients()
 	if err != nil {
 		r.l.Error(err, "get svc clients")
-		return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+		return r.reconcileFailed(ctx, cr, err)
 	}
 	for _, c := range sc {
 		defer c.Close()
@@ -116,6 +171,11 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		r.l.Info("reconcile delete started...")
 		// handle delete branch
 		deleteDAGCtx := r.ceCtx.GetDAGCtx(ccsyntax.FOWFor, gvk, ccsyntax.OperationDelete)
+		record := r.record.WithAnnotations(
+			pipelineAnnotation, deleteDAGCtx.DAG.GetRootVertex(),
+			operationAnnotation, string(ccsyntax.OperationDelete),
+		)
+		record.Event(cr, event.Normal(reasonPipelineStarted, "delete pipeline started"))
 
 		o := output.New()
 		result := result.New()
@@ -124,24 +184,48 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			Namespace:      req.Namespace,
 			Data:           x,
 			Client:         r.client,
+			Cache:          r.cache,
 			GVK:            gvk,
 			DAG:            deleteDAGCtx.DAG,
 			Output:         o,
 			Result:         result,
 			ServiceClients: sc,
+			MaxWorkers:     r.maxWorkers,
+			AllowWasm:      r.allowWasm,
 		})
 
 		// TODO should be per crName
 		e.Run(ctx)
 		//o.Print()
 		result.Print()
+		recordVertexFailures(record, cr, result)
+
+		// the resources in the inventory are pruned before the finalizer is
+		// removed, the resources that are only tracked by the owner labels
+		// are not garbage collected
+		prevInv, err := getInventory(cr)
+		if err != nil {
+			r.l.Error(err, "cannot get inventory, resources are not pruned")
+		}
+		inv, pruneErr := r.prune(ctx, record, cr, prevInv, inventory{})
+		if pruneErr != nil {
+			r.l.Error(pruneErr, "cannot prune resources")
+			if !inv.equal(prevInv) {
+				if err := r.updateInventory(ctx, cr, inv); err != nil {
+					r.l.Error(err, "cannot update inventory")
+				}
+			}
+			return r.reconcileFailed(ctx, cr, errors.Wrap(pruneErr, errPrune))
+		}
 
 		if err := r.f.RemoveFinalizer(ctx, cr); err != nil {
 			r.l.Error(err, "cannot remove finalizer")
+			record.Event(cr, event.Warning(reasonCannotRemoveFinalizer, err))
 			//managed.SetConditions(nddv1.ReconcileError(err), nddv1.Unknown())
 			return reconcile.Result{Requeue: true}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
 		}
 
+		record.Event(cr, event.Normal(reasonDeleted, "delete pipeline finished"))
 		r.l.Info("reconcile delete finished...")
 
 		return reconcile.Result{}, nil
@@ -149,6 +233,11 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	// apply branch -> used for create and update
 	r.l.Info("reconcile apply started...")
 	applyDAGCtx := r.ceCtx.GetDAGCtx(ccsyntax.FOWFor, gvk, ccsyntax.OperationApply)
+	record := r.record.WithAnnotations(
+		pipelineAnnotation, applyDAGCtx.DAG.GetRootVertex(),
+		operationAnnotation, string(ccsyntax.OperationApply),
+	)
+	record.Event(cr, event.Normal(reasonPipelineStarted, "apply pipeline started"))
 
 	o := output.New()
 	result := result.New()
@@ -157,30 +246,43 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		Namespace:      req.Namespace,
 		Data:           x,
 		Client:         r.client,
+		Cache:          r.cache,
 		GVK:            gvk,
 		DAG:            applyDAGCtx.DAG,
 		Output:         o,
 		Result:         result,
 		ServiceClients: sc,
+		MaxWorkers:     r.maxWorkers,
+		AllowWasm:      r.allowWasm,
 	})
 
 	e.Run(ctx)
 	//o.Print()
 	result.Print()
+	recordVertexFailures(record, cr, result)
 
-	// TODO check result if failed, return an error
-
-	for _, output := range o.GetFinalOutput() {
-		b, err := json.MarshalIndent(output, "", "  ")
+	prevInv, err := getInventory(cr)
+	if err != nil {
+		r.l.Error(err, "cannot get inventory, resources are not pruned")
+	}
+	inv := inventory{}
+	// cr is replaced by the output of the for resource, the owner of the own
+	// resources is the resource that was read
+	owner := cr
+	ownGVKs := r.ceCtx.GetFOW(ccsyntax.FOWOwn)
+	conflicts := []any{}
+	applied := 0
+	for _, fr := range getFinalResources(o) {
+		b, err := json.MarshalIndent(fr.data, "", "  ")
 		if err != nil {
 			r.l.Error(err, "cannot marshal the content")
-			return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+			return r.reconcileFailed(ctx, cr, err)
 		}
 		//r.l.Info("final output", "jsin string", string(b))
 		u := &unstructured.Unstructured{}
 		if err := json.Unmarshal(b, u); err != nil {
 			r.l.Error(err, "cannot unmarshal the content")
-			return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+			return r.reconcileFailed(ctx, cr, err)
 		}
 		r.l.Info("final output", "unstructured", u)
 
@@ -189,32 +291,254 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		if u.GroupVersionKind() == cr.GroupVersionKind() {
 			cr = u
 		} else {
-			if err := r.client.Apply(ctx, u); err != nil {
-				r.l.Error(err, "cannot apply the content")
-				return reconcile.Result{RequeueAfter: 5 * time.Second}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+			if _, ok := ownGVKs[u.GroupVersionKind()]; ok {
+				if err := r.setOwner(owner, u); err != nil {
+					r.l.Error(err, "cannot set owner")
+					return r.reconcileFailed(ctx, cr, err)
+				}
+			}
+			if err := r.apply(ctx, u, fr.forceConflicts); err != nil {
+				if !apierrors.IsConflict(err) {
+					r.l.Error(err, "cannot apply the content")
+					return r.reconcileFailed(ctx, cr, err)
+				}
+				// a conflict with another field manager does not stop the
+				// other resources from being applied
+				r.l.Info("apply conflict", "resource", getObjectRef(u).String(), "error", err.Error())
+				record.Event(owner, event.Warning(reasonApplyConflict, errors.Wrapf(err, "cannot apply resource %s", getObjectRef(u).String())))
+				conflicts = append(conflicts, getConflict(u, err))
+			} else {
+				applied++
+			}
+			if _, ok := r.noPrune[u.GroupVersionKind()]; !ok {
+				inv.add(getObjectRef(u))
 			}
 		}
 	}
 
+	if err := setConflicts(cr, conflicts); err != nil {
+		r.l.Error(err, "cannot set conflicts")
+	}
+
+	// the output of a failed pipeline is incomplete, so the resources are
+	// only pruned after a successful run
+	if !result.Success() {
+		for ref := range prevInv {
+			inv.add(ref)
+		}
+	}
+	inv, pruneErr := r.prune(ctx, record, owner, prevInv, inv)
+	if !inv.equal(prevInv) {
+		if err := r.updateInventory(ctx, cr, inv); err != nil {
+			r.l.Error(err, "cannot update inventory")
+			return r.reconcileFailed(ctx, cr, err)
+		}
+	}
+	if pruneErr != nil {
+		r.l.Error(pruneErr, "cannot prune resources")
+		return r.reconcileFailed(ctx, cr, errors.Wrap(pruneErr, errPrune))
+	}
+
+	if err := setPipelineResult(cr, result, len(conflicts)); err != nil {
+		r.l.Error(err, errConditions)
+	}
+	// a failed pipeline is retried with an exponential backoff
+	if !result.Success() {
+		msg := getPipelineFailure(result)
+		record.Event(owner, event.Event{Type: event.TypeWarning, Reason: reasonPipelineFailed, Message: msg})
+		if err := r.client.Status().Update(ctx, cr); err != nil {
+			r.l.Error(err, errUpdateStatus)
+		}
+		return reconcile.Result{}, errors.Errorf("%s: %s", errPipeline, msg)
+	}
+
+	record.Event(owner, event.Normal(reasonApplied, fmt.Sprintf("applied %d resources", applied)))
 	r.l.Info("reconcile apply finsihed...")
-	return reconcile.Result{}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
+	return reconcile.Result{RequeueAfter: r.getPollInterval(cr)}, errors.Wrap(r.client.Status().Update(ctx, cr), errUpdateStatus)
 }
 
-func (r *reconciler) getSvcClients() (map[schema.GroupVersionKind]svcclient.ServiceClient, error) {
-	// get a service client for each service instance
-	sc := map[schema.GroupVersionKind]svcclient.ServiceClient{}
-	for gvk, svcCtx := range r.ceCtx.GetServices().Get() {
-		svcClient, err := svcclient.New(&svcclient.Config{
-			Address:  strings.Join([]string{"127.0.0.1", strconv.Itoa(svcCtx.Port)}, ":"),
-			Insecure: true,
-		})
+// reconcileFailed records the error as an event, reports it in the conditions
+// of the resource and updates the status of the resource. The error is
+// returned so the request is retried with the exponential backoff of the
+// controller.
+func (r *reconciler) reconcileFailed(ctx context.Context, cr *unstructured.Unstructured, err error) (reconcile.Result, error) {
+	r.record.Event(cr, event.Warning(reasonReconcileError, err))
+	if err := setReconcileError(cr, err); err != nil {
+		r.l.Error(err, errConditions)
+	}
+	if err := r.client.Status().Update(ctx, cr); err != nil {
+		r.l.Error(err, errUpdateStatus)
+	}
+	return reconcile.Result{}, errors.Wrap(err, errReconcile)
+}
+
+// finalResource is a resource of the final output with the apply policy of
+// the output it belongs to
+type finalResource struct {
+	data           any
+	forceConflicts bool
+}
+
+func getFinalResources(o output.Output) []finalResource {
+	frs := []finalResource{}
+	for _, oi := range o.GetFinalOutputInfo() {
+		d, ok := oi.Data.([]any)
+		if !ok {
+			continue
+		}
+		for _, data := range d {
+			frs = append(frs, finalResource{data: data, forceConflicts: oi.ForceConflicts})
+		}
+	}
+	return frs
+}
+
+// apply applies the resource with server-side apply, the fields managed by
+// another field manager are taken over when forceConflicts is set
+func (r *reconciler) apply(ctx context.Context, u *unstructured.Unstructured, forceConflicts bool) error {
+	if forceConflicts {
+		return r.forceApply.Apply(ctx, u)
+	}
+	return r.client.Apply(ctx, u)
+}
+
+// getConflict returns the status entry of an apply conflict of the resource
+func getConflict(u *unstructured.Unstructured, err error) map[string]any {
+	return map[string]any{
+		"apiVersion": u.GetAPIVersion(),
+		"kind":       u.GetKind(),
+		"namespace":  u.GetNamespace(),
+		"name":       u.GetName(),
+		"message":    err.Error(),
+	}
+}
+
+// setConflicts reports the apply conflicts in the status of the resource
+func setConflicts(cr *unstructured.Unstructured, conflicts []any) error {
+	if len(conflicts) == 0 {
+		unstructured.RemoveNestedField(cr.Object, "status", "conflicts")
+		return nil
+	}
+	return unstructured.SetNestedSlice(cr.Object, conflicts, "status", "conflicts")
+}
+
+// setOwner sets the owner as the controller of the resource, a controller
+// reference that is set by a function is kept. A namespaced owner can only be
+// referenced by resources in its namespace, the other resources track their
+// owner with labels.
+func (r *reconciler) setOwner(owner, u *unstructured.Unstructured) error {
+	namespaced, err := meta.IsNamespaced(r.client.RESTMapper(), u.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	if !meta.CanOwn(owner, namespaced, u.GetNamespace()) {
+		return meta.AddOwnerLabels(u, owner, owner.GroupVersionKind().GroupKind())
+	}
+	if metav1.GetControllerOf(u) != nil {
+		return nil
+	}
+	u.SetOwnerReferences(append(u.GetOwnerReferences(), *metav1.NewControllerRef(owner, owner.GroupVersionKind())))
+	return nil
+}
+
+// prune deletes the resources of the previous inventory that are not in the
+// inventory of the current run and returns the inventory to record. The
+// resources that are not deleted due to a dry run or an error are kept in
+// the inventory.
+func (r *reconciler) prune(ctx context.Context, record event.Recorder, owner runtime.Object, prevInv, inv inventory) (inventory, error) {
+	var pruneErr error
+	for ref := range prevInv {
+		if inv.has(ref) {
+			continue
+		}
+		if _, ok := r.noPrune[ref.GroupVersionKind()]; ok {
+			continue
+		}
+		if r.pruneDryRun {
+			r.l.Info("dry run, resource would be pruned", "resource", ref.String())
+			record.Event(owner, event.Normal(reasonPruneDryRun, "dry run, would prune resource "+ref.String()))
+			inv.add(ref)
+			continue
+		}
+		r.l.Info("prune resource", "resource", ref.String())
+		if err := r.client.Delete(ctx, ref.getUnstructured()); meta.IgnoreNotFound(err) != nil {
+			r.l.Error(err, "cannot prune resource", "resource", ref.String())
+			err = errors.Wrapf(err, "cannot prune resource %s", ref.String())
+			record.Event(owner, event.Warning(reasonCannotPrune, err))
+			inv.add(ref)
+			if pruneErr == nil {
+				pruneErr = err
+			}
+			continue
+		}
+		record.Event(owner, event.Normal(reasonPruned, "pruned resource "+ref.String()))
+	}
+	return inv, pruneErr
+}
+
+// updateInventory records the inventory in the annotation of the resource,
+// the resource version of the resource is updated so the status of the
+// resource can be updated afterwards
+func (r *reconciler) updateInventory(ctx context.Context, cr *unstructured.Unstructured, inv inventory) error {
+	// an empty inventory removes the annotation
+	var v any
+	if len(inv) != 0 {
+		s, err := inv.marshal()
 		if err != nil {
-			r.l.Error(err, "cannot create new client")
-			return nil, err
+			return err
 		}
+		v = s
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				inventoryAnnotation: v,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	o := meta.GetUnstructuredFromGVK(r.ceCtx.GetForGVK())
+	o.SetNamespace(cr.GetNamespace())
+	o.SetName(cr.GetName())
+	if err := r.client.Patch(ctx, o, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return err
+	}
+	cr.SetResourceVersion(o.GetResourceVersion())
+	return nil
+}
+
+// getPollInterval returns the interval after which the resource is reconciled
+// again to correct drift, a jitter is added to spread the polls of the
+// resources. The interval of the resource annotation takes precedence.
+func (r *reconciler) getPollInterval(cr *unstructured.Unstructured) time.Duration {
+	interval := r.pollInterval
+	if v, ok := cr.GetAnnotations()[pollIntervalAnnotation]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			r.l.Error(err, "invalid poll interval annotation, using the default", "annotation", v)
+		} else {
+			interval = d
+		}
+	}
+	// a zero interval disables polling
+	if interval <= 0 {
+		return 0
+	}
+	return wait.Jitter(interval, pollJitter)
+}
+
+func (r *reconciler) getSvcClients() (map[schema.GroupVersionKind]svcclient.ServiceClient, error) {
+	// get a service client for each service instance
+	sc, err := service.GetClients(r.ceCtx.GetServices())
+	if err != nil {
+		r.l.Error(err, "cannot create new client")
+		return nil, err
+	}
+	for gvk, svcClient := range sc {
 		r.l.Info("svc client create", "gvk", gvk.String())
 		fmt.Printf("client create: client: %v\n", svcClient.Get())
-		sc[gvk] = svcClient
 	}
 	return sc, nil
 }
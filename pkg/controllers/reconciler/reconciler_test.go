@@ -0,0 +1,164 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/applicator"
+	"github.com/yndd/lcnc-runtime/pkg/exec/output"
+	"github.com/yndd/lcnc-runtime/pkg/meta"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newResource(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+var _ = Describe("Reconciler", func() {
+	Describe("setOwner", func() {
+		var (
+			r     *reconciler
+			owner *unstructured.Unstructured
+		)
+
+		BeforeEach(func() {
+			mapper := apimeta.NewDefaultRESTMapper(nil)
+			mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+			mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+			r = &reconciler{
+				client: applicator.ClientApplicator{Client: fake.NewClientBuilder().WithRESTMapper(mapper).Build()},
+			}
+			owner = newResource("example.com/v1", "App", "default", "owner")
+			owner.SetUID(types.UID("1234"))
+		})
+
+		It("should set a controller reference in the namespace of the owner", func() {
+			u := newResource("v1", "ConfigMap", "default", "a")
+			Expect(r.setOwner(owner, u)).To(Succeed())
+			Expect(u.GetOwnerReferences()).To(Equal([]metav1.OwnerReference{{
+				APIVersion:         "example.com/v1",
+				Kind:               "App",
+				Name:               "owner",
+				UID:                types.UID("1234"),
+				Controller:         pointer.Bool(true),
+				BlockOwnerDeletion: pointer.Bool(true),
+			}}))
+			Expect(u.GetLabels()).To(BeEmpty())
+		})
+
+		It("should keep the controller reference set by a function", func() {
+			u := newResource("v1", "ConfigMap", "default", "a")
+			other := newResource("example.com/v1", "Other", "default", "other")
+			u.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(other, other.GroupVersionKind())})
+			Expect(r.setOwner(owner, u)).To(Succeed())
+			Expect(u.GetOwnerReferences()).To(HaveLen(1))
+			Expect(u.GetOwnerReferences()[0].Kind).To(Equal("Other"))
+		})
+
+		It("should track the owner with labels in another namespace", func() {
+			u := newResource("v1", "ConfigMap", "other", "a")
+			Expect(r.setOwner(owner, u)).To(Succeed())
+			Expect(u.GetOwnerReferences()).To(BeEmpty())
+			nsn, ok := meta.GetOwnerFromLabels(u, owner.GroupVersionKind().GroupKind())
+			Expect(ok).To(BeTrue())
+			Expect(nsn).To(Equal(types.NamespacedName{Namespace: "default", Name: "owner"}))
+		})
+
+		It("should track the owner with labels for a cluster scoped resource", func() {
+			u := newResource("v1", "Namespace", "", "a")
+			Expect(r.setOwner(owner, u)).To(Succeed())
+			Expect(u.GetOwnerReferences()).To(BeEmpty())
+			Expect(u.GetLabels()).To(HaveKeyWithValue(meta.LabelKeyOwnerName, "owner"))
+		})
+
+		It("should fail for an unknown resource", func() {
+			u := newResource("v1", "Unknown", "default", "a")
+			Expect(r.setOwner(owner, u)).NotTo(Succeed())
+		})
+	})
+
+	Describe("getFinalResources", func() {
+		It("should return the resources of the external outputs with their apply policy", func() {
+			o := output.New()
+			o.AddEntry("a", &output.OutputInfo{Data: []any{"a1", "a2"}})
+			o.AddEntry("b", &output.OutputInfo{ForceConflicts: true, Data: []any{"b1"}})
+			o.AddEntry("c", &output.OutputInfo{Internal: true, ForceConflicts: true, Data: []any{"c1"}})
+			Expect(getFinalResources(o)).To(ConsistOf(
+				finalResource{data: "a1"},
+				finalResource{data: "a2"},
+				finalResource{data: "b1", forceConflicts: true},
+			))
+		})
+	})
+
+	Describe("apply", func() {
+		var (
+			r       *reconciler
+			applied []string
+		)
+
+		newApplicator := func(name string) applicator.Applicator {
+			return applicator.ApplyFn(func(_ context.Context, _ client.Object, _ ...applicator.ApplyOption) error {
+				applied = append(applied, name)
+				return nil
+			})
+		}
+
+		BeforeEach(func() {
+			applied = []string{}
+			r = &reconciler{
+				client:     applicator.ClientApplicator{Applicator: newApplicator("apply")},
+				forceApply: newApplicator("force"),
+			}
+		})
+
+		It("should apply without forcing conflicts", func() {
+			Expect(r.apply(context.Background(), newResource("v1", "ConfigMap", "default", "a"), false)).To(Succeed())
+			Expect(applied).To(Equal([]string{"apply"}))
+		})
+
+		It("should apply with forcing conflicts", func() {
+			Expect(r.apply(context.Background(), newResource("v1", "ConfigMap", "default", "a"), true)).To(Succeed())
+			Expect(applied).To(Equal([]string{"force"}))
+		})
+	})
+
+	Describe("setConflicts", func() {
+		It("should report the conflicts in the status", func() {
+			cr := newResource("example.com/v1", "App", "default", "owner")
+			u := newResource("v1", "ConfigMap", "default", "a")
+			conflicts := []any{getConflict(u, fmt.Errorf("conflict"))}
+			Expect(setConflicts(cr, conflicts)).To(Succeed())
+			Expect(cr.Object["status"]).To(Equal(map[string]any{
+				"conflicts": []any{map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"namespace":  "default",
+					"name":       "a",
+					"message":    "conflict",
+				}},
+			}))
+		})
+
+		It("should remove the conflicts that are resolved", func() {
+			cr := newResource("example.com/v1", "App", "default", "owner")
+			cr.Object["status"] = map[string]any{"conflicts": []any{"old"}, "ready": true}
+			Expect(setConflicts(cr, []any{})).To(Succeed())
+			Expect(cr.Object["status"]).To(Equal(map[string]any{"ready": true}))
+		})
+	})
+})
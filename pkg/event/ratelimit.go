@@ -0,0 +1,117 @@
+/*
+Copyright 2021 NDD.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+	http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// A RateLimitedRecorder records an event at most once per interval. Events
+// are the same when they relate to the same object and have the same type,
+// reason, message and annotations.
+type RateLimitedRecorder struct {
+	r           Recorder
+	annotations map[string]string
+	limiter     *limiter
+}
+
+// NewRateLimitedRecorder returns a RateLimitedRecorder that records the events
+// that are not the same as an event recorded within the supplied interval
+// using the supplied Recorder.
+func NewRateLimitedRecorder(r Recorder, interval time.Duration) *RateLimitedRecorder {
+	return &RateLimitedRecorder{
+		r:           r,
+		annotations: map[string]string{},
+		limiter:     &limiter{interval: interval, recorded: map[string]time.Time{}},
+	}
+}
+
+// Event records the supplied event unless the same event was recorded within
+// the interval.
+func (r *RateLimitedRecorder) Event(obj runtime.Object, e Event) {
+	if !r.limiter.allow(r.key(obj, e), time.Now()) {
+		return
+	}
+	r.r.Event(obj, e)
+}
+
+// WithAnnotations returns a new *RateLimitedRecorder that includes the
+// supplied annotations with all recorded events. The new recorder shares the
+// interval of the recorded events with this recorder.
+func (r *RateLimitedRecorder) WithAnnotations(keysAndValues ...string) Recorder {
+	rr := &RateLimitedRecorder{
+		r:           r.r.WithAnnotations(keysAndValues...),
+		annotations: map[string]string{},
+		limiter:     r.limiter,
+	}
+	for k, v := range r.annotations {
+		rr.annotations[k] = v
+	}
+	sliceMap(keysAndValues, rr.annotations)
+	return rr
+}
+
+func (r *RateLimitedRecorder) key(obj runtime.Object, e Event) string {
+	annotations := make([]string, 0, len(r.annotations)+len(e.Annotations))
+	for k, v := range r.annotations {
+		annotations = append(annotations, k+"="+v)
+	}
+	for k, v := range e.Annotations {
+		annotations = append(annotations, k+"="+v)
+	}
+	sort.Strings(annotations)
+
+	var uid string
+	if m, err := meta.Accessor(obj); err == nil {
+		uid = string(m.GetUID())
+	}
+	return strings.Join(append([]string{uid, string(e.Type), string(e.Reason), e.Message}, annotations...), "/")
+}
+
+type limiter struct {
+	m         sync.Mutex
+	interval  time.Duration
+	recorded  map[string]time.Time
+	lastSweep time.Time
+}
+
+// allow returns true when the event with the key was not recorded within the
+// interval and records the event at the supplied time.
+func (l *limiter) allow(key string, now time.Time) bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	// the events that are recorded before the interval are removed so the
+	// recorded events do not grow unbounded
+	if now.Sub(l.lastSweep) > l.interval {
+		for k, t := range l.recorded {
+			if now.Sub(t) > l.interval {
+				delete(l.recorded, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	if t, ok := l.recorded[key]; ok && now.Sub(t) <= l.interval {
+		return false
+	}
+	l.recorded[key] = now
+	return true
+}
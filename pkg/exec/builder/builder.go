@@ -6,6 +6,7 @@ import (
 	"github.com/yndd/lcnc-runtime/pkg/exec/exechandler"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap/functions"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
 	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
@@ -23,6 +24,13 @@ type Config struct {
 	Output         output.Output
 	Result         result.Result
 	ServiceClients map[schema.GroupVersionKind]svcclient.ServiceClient
+	// Cache is the informer cache the query functions read from, when not
+	// set the queries read from the client
+	Cache client.Reader
+	// MaxWorkers is the maximum number of functions that run concurrently
+	MaxWorkers int
+	// AllowWasm enables wasm functions
+	AllowWasm bool
 }
 
 func New(c *Config) executor.Executor {
@@ -34,9 +42,14 @@ func New(c *Config) executor.Executor {
 		Namespace:      c.Namespace,
 		RootVertexName: rootVertexName,
 		Client:         c.Client,
+		Cache:          c.Cache,
 		Output:         c.Output,
 		Result:         c.Result,
 		ServiceClients: c.ServiceClients,
+		RunnerOptions: fnruntime.RunnerOptions{
+			AllowWasm: c.AllowWasm,
+		},
+		MaxWorkers: c.MaxWorkers,
 	})
 
 	// Initialize the initial data
@@ -48,18 +61,21 @@ func New(c *Config) executor.Executor {
 
 	// initialize the handler
 	h := exechandler.New(&exechandler.Config{
-		Name:   rootVertexName,
-		Type:   result.ExecRootType,
-		DAG:    c.DAG,
-		FnMap:  fnmap,
-		Output: c.Output,
-		Result: c.Result,
+		Name:           rootVertexName,
+		RootVertexName: rootVertexName,
+		Type:           result.ExecRootType,
+		DAG:            c.DAG,
+		FnMap:          fnmap,
+		Output:         c.Output,
+		Result:         c.Result,
 	})
 
 	return executor.New(c.DAG, &executor.Config{
 		Name:               rootVertexName,
 		From:               rootVertexName,
+		MaxWorkers:         c.MaxWorkers,
 		VertexFuntionRunFn: h.FunctionRun,
+		VertexSkipFn:       h.RecordSkip,
 		ExecPostRunFn:      h.RecordFinalResult,
 	})
 }
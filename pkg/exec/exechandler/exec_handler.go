@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-logr/logr"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
@@ -17,17 +19,23 @@ import (
 )
 
 type ExecHandler interface {
-	FunctionRun(ctx context.Context, vertexName string, vertexContext any) bool
+	FunctionRun(ctx context.Context, vertexName string, vertexContext any) executor.VertexOutcome
+	// RecordSkip records a vertex that did not run since one of its
+	// dependencies did not succeed
+	RecordSkip(vertexName string, vertexContext any, reason string)
 	RecordFinalResult(start, finish time.Time, success bool)
 }
 
 type Config struct {
-	Name   string
-	Type   result.ExecType
-	DAG    rtdag.RuntimeDAG
-	FnMap  fnmap.FuncMap
-	Output output.Output
-	Result result.Result
+	Name string
+	// RootVertexName is the root vertex of the pipeline, which differs
+	// from the root vertex of the DAG when the DAG belongs to a block
+	RootVertexName string
+	Type           result.ExecType
+	DAG            rtdag.RuntimeDAG
+	FnMap          fnmap.FuncMap
+	Output         output.Output
+	Result         result.Result
 }
 
 func New(c *Config) ExecHandler {
@@ -42,11 +50,14 @@ type execHandler struct {
 	l   logr.Logger
 }
 
-func (r *execHandler) FunctionRun(ctx context.Context, vertexName string, vertexContext any) bool {
+func (r *execHandler) FunctionRun(ctx context.Context, vertexName string, vertexContext any) executor.VertexOutcome {
 	start := time.Now()
-	success := true
+	outcome := executor.VertexSucceeded
 	reason := ""
-	rootVertexName := r.cfg.DAG.GetRootVertex()
+	rootVertexName := r.cfg.RootVertexName
+	if rootVertexName == "" {
+		rootVertexName = r.cfg.DAG.GetRootVertex()
+	}
 
 	r.l.WithValues("execName", rootVertexName, "vertexName", vertexName)
 
@@ -60,9 +71,11 @@ func (r *execHandler) FunctionRun(ctx context.Context, vertexName string, vertex
 			VertexName: vertexName,
 			StartTime:  start,
 			EndTime:    time.Now(),
+			Outcome:    executor.VertexFailed,
 			Success:    false,
 			Reason:     err.Error(),
 		})
+		return executor.VertexFailed
 	}
 
 	// Gather the input based on the function type
@@ -84,39 +97,167 @@ func (r *execHandler) FunctionRun(ctx context.Context, vertexName string, vertex
 	}
 	//i.Print(vertexName)
 
-	o, err := r.cfg.FnMap.Run(ctx, vc, i)
+	// run the function, a failed attempt is retried according to the
+	// retry policy of the function
+	p := getRetryPolicy(&vc.Function)
+	attempts := make([]*result.AttemptInfo, 0, p.attempts)
+	var o output.Output
+	var blockResult result.Result
+	var err error
+	for attempt := 1; ; attempt++ {
+		fnMap := r.cfg.FnMap
+		if vc.Function.Type == ctrlcfgv1.BlockType {
+			// the functions within the block record their results in a
+			// nested result, which is attached to the result of the block
+			blockResult = result.New()
+			fnMap = fnMap.WithResult(blockResult)
+		}
+		attemptStart := time.Now()
+		o, err = r.runAttempt(ctx, fnMap, vc, i, p.timeout)
+		ai := &result.AttemptInfo{
+			Attempt:   attempt,
+			StartTime: attemptStart,
+			EndTime:   time.Now(),
+			Success:   err == nil || errors.Is(err, ErrConditionFalse),
+		}
+		if err != nil {
+			ai.Reason = err.Error()
+		}
+		attempts = append(attempts, ai)
+		if ai.Success || attempt >= p.attempts {
+			break
+		}
+		r.l.Info("attempt failed, retrying", "vertexName", vertexName, "attempt", attempt, "error", err.Error())
+		if werr := p.wait(ctx); werr != nil {
+			// the run got cancelled while waiting for the next attempt
+			break
+		}
+	}
 	if err != nil {
-		if !errors.Is(err, ErrConditionFalse) {
-			success = false
+		// a false condition skips the vertex and its dependents
+		outcome = executor.VertexFailed
+		if errors.Is(err, ErrConditionFalse) {
+			outcome = executor.VertexSkipped
 		}
 		reason = err.Error()
 	}
 
+	if outcome == executor.VertexSkipped {
+		// the vertices of a block with a false condition did not run
+		blockResult = getSkippedBlockResult(vc)
+	}
+
 	finished := time.Now()
 
 	r.cfg.Output.Add(o)
 
 	r.cfg.Result.Add(&result.ResultInfo{
-		Type:       r.cfg.Type,
-		ExecName:   r.cfg.Name,
-		VertexName: vertexName,
-		StartTime:  start,
-		EndTime:    finished,
-		Input:      i,
-		Output:     o,
-		Success:    success,
-		Reason:     reason,
+		Type:        r.cfg.Type,
+		ExecName:    r.cfg.Name,
+		VertexName:  vertexName,
+		StartTime:   start,
+		EndTime:     finished,
+		Input:       i,
+		Output:      o,
+		Outcome:     outcome,
+		Success:     outcome != executor.VertexFailed,
+		Reason:      reason,
+		BlockResult: blockResult,
+		Attempts:    attempts,
+	})
+	return outcome
+}
+
+func (r *execHandler) RecordSkip(vertexName string, vertexContext any, reason string) {
+	var blockResult result.Result
+	if vc, ok := vertexContext.(*rtdag.VertexContext); ok {
+		blockResult = getSkippedBlockResult(vc)
+	}
+	now := time.Now()
+	r.cfg.Result.Add(&result.ResultInfo{
+		Type:        r.cfg.Type,
+		ExecName:    r.cfg.Name,
+		VertexName:  vertexName,
+		StartTime:   now,
+		EndTime:     now,
+		Outcome:     executor.VertexSkipped,
+		Success:     true,
+		Reason:      reason,
+		BlockResult: blockResult,
 	})
-	return success
+}
+
+// getSkippedBlockResult returns the result of the block of a skipped vertex,
+// the vertices of the block and of its nested blocks are recorded as skipped
+// such that the result accounts for every vertex of the pipeline
+func getSkippedBlockResult(vc *rtdag.VertexContext) result.Result {
+	if vc.BlockDAG == nil {
+		return nil
+	}
+	return getSkippedResult(vc.BlockDAG, fmt.Sprintf("block %s skipped", vc.VertexName))
+}
+
+func getSkippedResult(d rtdag.RuntimeDAG, reason string) result.Result {
+	res := result.New()
+	execName := d.GetRootVertex()
+	vertices := d.GetVertices()
+	vertexNames := make([]string, 0, len(vertices))
+	for vertexName := range vertices {
+		vertexNames = append(vertexNames, vertexName)
+	}
+	sort.Strings(vertexNames)
+	for _, vertexName := range vertexNames {
+		var blockResult result.Result
+		if vc, ok := vertices[vertexName].(*rtdag.VertexContext); ok && vc.BlockDAG != nil {
+			blockResult = getSkippedResult(vc.BlockDAG, reason)
+		}
+		now := time.Now()
+		res.Add(&result.ResultInfo{
+			Type:        result.ExecBlockType,
+			ExecName:    execName,
+			VertexName:  vertexName,
+			StartTime:   now,
+			EndTime:     now,
+			Outcome:     executor.VertexSkipped,
+			Success:     true,
+			Reason:      reason,
+			BlockResult: blockResult,
+		})
+	}
+	return res
+}
+
+// runAttempt runs a single attempt of the function in the caller, when a
+// timeout is supplied the attempt gets its own context which is cancelled
+// when the timeout expires. The functions honour the context, so an attempt
+// has finished when runAttempt returns and attempts never overlap. The
+// output of an attempt that timed out is discarded.
+func (r *execHandler) runAttempt(ctx context.Context, fnMap fnmap.FuncMap, vc *rtdag.VertexContext, i input.Input, timeout time.Duration) (output.Output, error) {
+	if timeout == 0 {
+		return fnMap.Run(ctx, vc, i)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	o, err := fnMap.Run(ctx, vc, i)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("function timed out after %s", timeout)
+	}
+	return o, err
 }
 
 func (r *execHandler) RecordFinalResult(start, finish time.Time, success bool) {
+	outcome := executor.VertexSucceeded
+	if !success {
+		outcome = executor.VertexFailed
+	}
 	r.cfg.Result.Add(&result.ResultInfo{
 		Type:       result.ExecRootType,
 		ExecName:   r.cfg.DAG.GetRootVertex(),
 		VertexName: "total",
 		StartTime:  start,
 		EndTime:    finish,
+		Outcome:    outcome,
 		Success:    success,
 	})
 }
@@ -0,0 +1,284 @@
+package exechandler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
+	"github.com/yndd/lcnc-runtime/pkg/exec/exechandler"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/input"
+	"github.com/yndd/lcnc-runtime/pkg/exec/output"
+	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeFnMap runs runFn for every attempt and records how many attempts
+// were called and how many of them ran at the same time
+type fakeFnMap struct {
+	m          sync.Mutex
+	calls      int
+	running    int
+	maxRunning int
+	runFn      func(ctx context.Context, call int) (output.Output, error)
+}
+
+func (r *fakeFnMap) Register(fnType ctrlcfgv1.FunctionType, initFn fnmap.Initializer) {}
+
+func (r *fakeFnMap) WithOutput(o output.Output) fnmap.FuncMap { return r }
+
+func (r *fakeFnMap) WithResult(res result.Result) fnmap.FuncMap { return r }
+
+func (r *fakeFnMap) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
+	r.m.Lock()
+	r.calls++
+	call := r.calls
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.m.Unlock()
+	defer func() {
+		r.m.Lock()
+		r.running--
+		r.m.Unlock()
+	}()
+	return r.runFn(ctx, call)
+}
+
+func newVertexContext(timeout time.Duration, retries int) *rtdag.VertexContext {
+	fn := ctrlcfgv1.Function{
+		Type:    ctrlcfgv1.JQType,
+		Retries: retries,
+		Backoff: &ctrlcfgv1.Backoff{
+			Duration: &metav1.Duration{Duration: time.Millisecond},
+		},
+	}
+	if timeout > 0 {
+		fn.Timeout = &metav1.Duration{Duration: timeout}
+	}
+	return &rtdag.VertexContext{
+		VertexName: "fn",
+		Function:   fn,
+	}
+}
+
+func runFunction(fm fnmap.FuncMap, vc *rtdag.VertexContext) (executor.VertexOutcome, *result.ResultInfo) {
+	res := result.New()
+	h := exechandler.New(&exechandler.Config{
+		Name:           "root",
+		RootVertexName: "root",
+		Type:           result.ExecRootType,
+		FnMap:          fm,
+		Output:         output.New(),
+		Result:         res,
+	})
+	outcome := h.FunctionRun(context.Background(), vc.VertexName, vc)
+	Expect(res.Length()).To(Equal(1))
+	ri, ok := res.Get()[0].(*result.ResultInfo)
+	Expect(ok).To(BeTrue())
+	return outcome, ri
+}
+
+// newBlockVertexContext returns the vertex context of a block with a
+// nested block
+func newBlockVertexContext() *rtdag.VertexContext {
+	innerBlockDAG := rtdag.New()
+	Expect(innerBlockDAG.AddVertex("innerBlockRoot", &rtdag.VertexContext{
+		VertexName: "innerBlockRoot",
+		Kind:       rtdag.RootVertexKind,
+	})).To(Succeed())
+	Expect(innerBlockDAG.AddVertex("innerTask", &rtdag.VertexContext{
+		VertexName: "innerTask",
+		Kind:       rtdag.FunctionVertexKind,
+	})).To(Succeed())
+	blockDAG := rtdag.New()
+	Expect(blockDAG.AddVertex("blockRoot", &rtdag.VertexContext{
+		VertexName: "blockRoot",
+		Kind:       rtdag.RootVertexKind,
+	})).To(Succeed())
+	Expect(blockDAG.AddVertex("innerBlock", &rtdag.VertexContext{
+		VertexName: "innerBlock",
+		Kind:       rtdag.FunctionVertexKind,
+		BlockDAG:   innerBlockDAG,
+	})).To(Succeed())
+	return &rtdag.VertexContext{
+		VertexName: "block",
+		Kind:       rtdag.FunctionVertexKind,
+		Function:   ctrlcfgv1.Function{Type: ctrlcfgv1.BlockType},
+		BlockDAG:   blockDAG,
+	}
+}
+
+// getSkipped returns the skip reasons of the skipped vertices in the result
+// and in the nested block results by exec and vertex name
+func getSkipped(res result.Result) map[string]string {
+	skipped := map[string]string{}
+	for _, v := range res.Get() {
+		ri, ok := v.(*result.ResultInfo)
+		Expect(ok).To(BeTrue())
+		if ri.Outcome == executor.VertexSkipped {
+			skipped[ri.ExecName+"/"+ri.VertexName] = ri.Reason
+		}
+		if ri.BlockResult != nil {
+			for k, reason := range getSkipped(ri.BlockResult) {
+				skipped[k] = reason
+			}
+		}
+	}
+	return skipped
+}
+
+var _ = Describe("ExecHandler", func() {
+	Describe("FunctionRun", func() {
+		It("should record a single attempt when the function succeeds", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				return output.New(), nil
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(0, 2))
+			Expect(outcome).To(Equal(executor.VertexSucceeded))
+			Expect(fm.calls).To(Equal(1))
+			Expect(ri.Attempts).To(HaveLen(1))
+			Expect(ri.Attempts[0].Attempt).To(Equal(1))
+			Expect(ri.Attempts[0].Success).To(BeTrue())
+		})
+
+		It("should retry a failed function until it succeeds", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				if call < 3 {
+					return nil, errors.New("transient error")
+				}
+				return output.New(), nil
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(0, 3))
+			Expect(outcome).To(Equal(executor.VertexSucceeded))
+			Expect(ri.Success).To(BeTrue())
+			Expect(fm.calls).To(Equal(3))
+			Expect(ri.Attempts).To(HaveLen(3))
+			for n, ai := range ri.Attempts {
+				Expect(ai.Attempt).To(Equal(n + 1))
+			}
+			Expect(ri.Attempts[0].Success).To(BeFalse())
+			Expect(ri.Attempts[0].Reason).To(Equal("transient error"))
+			Expect(ri.Attempts[1].Success).To(BeFalse())
+			Expect(ri.Attempts[2].Success).To(BeTrue())
+		})
+
+		It("should fail after the retries are exhausted", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				return nil, errors.New("permanent error")
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(0, 2))
+			Expect(outcome).To(Equal(executor.VertexFailed))
+			Expect(ri.Success).To(BeFalse())
+			Expect(ri.Reason).To(Equal("permanent error"))
+			Expect(fm.calls).To(Equal(3))
+			Expect(ri.Attempts).To(HaveLen(3))
+			for _, ai := range ri.Attempts {
+				Expect(ai.Success).To(BeFalse())
+			}
+		})
+
+		It("should not retry when the condition is false", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				return output.New(), exechandler.ErrConditionFalse
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(0, 2))
+			Expect(outcome).To(Equal(executor.VertexSkipped))
+			Expect(fm.calls).To(Equal(1))
+			Expect(ri.Attempts).To(HaveLen(1))
+			Expect(ri.Attempts[0].Success).To(BeTrue())
+		})
+
+		It("should record the vertices of a block with a false condition as skipped", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				return output.New(), exechandler.ErrConditionFalse
+			}}
+			res := result.New()
+			h := exechandler.New(&exechandler.Config{
+				Name:           "root",
+				RootVertexName: "root",
+				Type:           result.ExecRootType,
+				FnMap:          fm,
+				Output:         output.New(),
+				Result:         res,
+			})
+			vc := newBlockVertexContext()
+			Expect(h.FunctionRun(context.Background(), vc.VertexName, vc)).To(Equal(executor.VertexSkipped))
+			Expect(getSkipped(res)).To(Equal(map[string]string{
+				"root/block":                    exechandler.ErrConditionFalse.Error(),
+				"blockRoot/blockRoot":           "block block skipped",
+				"blockRoot/innerBlock":          "block block skipped",
+				"innerBlockRoot/innerBlockRoot": "block block skipped",
+				"innerBlockRoot/innerTask":      "block block skipped",
+			}))
+		})
+
+		It("should fail an attempt that times out and retry it without overlap", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				if call == 1 {
+					// block until the attempt times out
+					<-ctx.Done()
+					o := output.New()
+					o.AddEntry("stale", true)
+					return o, ctx.Err()
+				}
+				return output.New(), nil
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(10*time.Millisecond, 1))
+			Expect(outcome).To(Equal(executor.VertexSucceeded))
+			Expect(fm.calls).To(Equal(2))
+			Expect(fm.maxRunning).To(Equal(1))
+			Expect(ri.Attempts).To(HaveLen(2))
+			Expect(ri.Attempts[0].Success).To(BeFalse())
+			Expect(ri.Attempts[0].Reason).To(Equal("function timed out after 10ms"))
+			Expect(ri.Attempts[0].EndTime.Sub(ri.Attempts[0].StartTime)).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(ri.Attempts[1].Success).To(BeTrue())
+			Expect(ri.Output.GetValue("stale")).To(BeNil())
+		})
+
+		It("should discard the output of a function that times out", func() {
+			fm := &fakeFnMap{runFn: func(ctx context.Context, call int) (output.Output, error) {
+				<-ctx.Done()
+				o := output.New()
+				o.AddEntry("stale", true)
+				return o, nil
+			}}
+			outcome, ri := runFunction(fm, newVertexContext(10*time.Millisecond, 0))
+			Expect(outcome).To(Equal(executor.VertexFailed))
+			Expect(ri.Output).To(BeNil())
+			Expect(ri.Reason).To(Equal("function timed out after 10ms"))
+			Expect(ri.Attempts).To(HaveLen(1))
+		})
+	})
+
+	Describe("RecordSkip", func() {
+		It("should record the vertices of a skipped block as skipped", func() {
+			res := result.New()
+			h := exechandler.New(&exechandler.Config{
+				Name:           "root",
+				RootVertexName: "root",
+				Type:           result.ExecRootType,
+				FnMap:          &fakeFnMap{},
+				Output:         output.New(),
+				Result:         res,
+			})
+			h.RecordSkip("block", newBlockVertexContext(), "dependency task failed")
+			// the vertices of the block are recorded in the nested block result
+			Expect(res.Length()).To(Equal(1))
+			Expect(getSkipped(res)).To(Equal(map[string]string{
+				"root/block":                    "dependency task failed",
+				"blockRoot/blockRoot":           "block block skipped",
+				"blockRoot/innerBlock":          "block block skipped",
+				"innerBlockRoot/innerBlockRoot": "block block skipped",
+				"innerBlockRoot/innerTask":      "block block skipped",
+			}))
+		})
+	})
+})
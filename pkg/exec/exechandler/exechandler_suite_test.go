@@ -0,0 +1,13 @@
+package exechandler_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestExechandler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Exechandler Suite")
+}
@@ -0,0 +1,69 @@
+package exechandler
+
+import (
+	"context"
+	"time"
+
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultBackoffDuration = 1 * time.Second
+	defaultBackoffFactor   = 2
+	defaultBackoffMax      = 30 * time.Second
+)
+
+// retryPolicy is the timeout, retry and backoff policy of a function
+type retryPolicy struct {
+	// timeout of a single attempt, 0 means no timeout
+	timeout time.Duration
+	// attempts is the total amount of attempts, including the first one
+	attempts int
+	backoff  wait.Backoff
+}
+
+func getRetryPolicy(fn *ctrlcfgv1.Function) *retryPolicy {
+	p := &retryPolicy{
+		attempts: 1,
+		backoff: wait.Backoff{
+			Duration: defaultBackoffDuration,
+			Factor:   defaultBackoffFactor,
+			Cap:      defaultBackoffMax,
+			// the steps are bounded by the amount of attempts
+			Steps: 1,
+		},
+	}
+	if fn.Timeout != nil && fn.Timeout.Duration > 0 {
+		p.timeout = fn.Timeout.Duration
+	}
+	if fn.Retries > 0 {
+		p.attempts += fn.Retries
+		p.backoff.Steps = fn.Retries
+	}
+	if fn.Backoff != nil {
+		if fn.Backoff.Duration != nil && fn.Backoff.Duration.Duration > 0 {
+			p.backoff.Duration = fn.Backoff.Duration.Duration
+		}
+		if fn.Backoff.Factor > 0 {
+			p.backoff.Factor = float64(fn.Backoff.Factor)
+		}
+		if fn.Backoff.Max != nil && fn.Backoff.Max.Duration > 0 {
+			p.backoff.Cap = fn.Backoff.Max.Duration
+		}
+	}
+	return p
+}
+
+// wait waits for the backoff delay before the next attempt, it returns
+// an error when the context is cancelled while waiting
+func (r *retryPolicy) wait(ctx context.Context) error {
+	t := time.NewTimer(r.backoff.Step())
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -20,6 +21,14 @@ type Initializer func() Function
 type FuncMap interface {
 	Register(fnType ctrlcfgv1.FunctionType, initFn Initializer)
 	Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error)
+	// WithOutput returns a function map with the same registered functions
+	// that provides the supplied output to the functions, this is used for
+	// the nested scope of a block
+	WithOutput(o output.Output) FuncMap
+	// WithResult returns a function map with the same registered functions
+	// that provides the supplied result to the functions, this is used to
+	// record the results of the functions within a block in a nested result
+	WithResult(r result.Result) FuncMap
 }
 
 type Config struct {
@@ -27,9 +36,16 @@ type Config struct {
 	Namespace      string
 	RootVertexName string
 	Client         client.Client
+	// Cache is the informer cache the query functions read from
+	Cache          client.Reader
 	Output         output.Output
 	Result         result.Result
 	ServiceClients map[schema.GroupVersionKind]svcclient.ServiceClient
+	// RunnerOptions are the options to run container and wasm functions
+	RunnerOptions fnruntime.RunnerOptions
+	// MaxWorkers is the maximum number of functions that run concurrently
+	// within a block
+	MaxWorkers int
 }
 
 func New(c *Config) FuncMap {
@@ -51,6 +67,31 @@ func (r *fnMap) Register(fnType ctrlcfgv1.FunctionType, initFn Initializer) {
 	r.funcs[fnType] = initFn
 }
 
+func (r *fnMap) WithOutput(o output.Output) FuncMap {
+	c := *r.cfg
+	c.Output = o
+	return r.withConfig(&c)
+}
+
+func (r *fnMap) WithResult(res result.Result) FuncMap {
+	c := *r.cfg
+	c.Result = res
+	return r.withConfig(&c)
+}
+
+func (r *fnMap) withConfig(c *Config) FuncMap {
+	fm := &fnMap{
+		cfg:   c,
+		funcs: map[ctrlcfgv1.FunctionType]Initializer{},
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	for fnType, initFn := range r.funcs {
+		fm.funcs[fnType] = initFn
+	}
+	return fm
+}
+
 func (r *fnMap) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.m.RLock()
 	initializer, ok := r.funcs[vertexContext.Function.Type]
@@ -66,13 +107,17 @@ func (r *fnMap) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i i
 	case ctrlcfgv1.BlockType:
 		fn.WithOutput(r.cfg.Output)
 		fn.WithResult(r.cfg.Result)
+		fn.WithRootVertexName(r.cfg.RootVertexName)
 		fn.WithFnMap(r)
+		fn.WithMaxWorkers(r.cfg.MaxWorkers)
 	case ctrlcfgv1.QueryType:
 		fn.WithClient(r.cfg.Client)
+		fn.WithCache(r.cfg.Cache)
 	case ctrlcfgv1.ContainerType, ctrlcfgv1.WasmType:
 		fn.WithNameAndNamespace(r.cfg.Name, r.cfg.Namespace)
 		fn.WithRootVertexName(r.cfg.RootVertexName)
 		fn.WithServiceClients(r.cfg.ServiceClients)
+		fn.WithRunnerOptions(r.cfg.RunnerOptions)
 	}
 	// run the function
 	return fn.Run(ctx, vertexContext, i)
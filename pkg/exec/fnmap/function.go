@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -19,9 +20,12 @@ type Function interface {
 	WithResult(result result.Result)
 	WithNameAndNamespace(name, namespace string)
 	WithClient(client client.Client)
+	WithCache(cache client.Reader)
 	WithFnMap(fnMap FuncMap)
 	WithRootVertexName(name string)
 	WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient)
+	WithRunnerOptions(opts fnruntime.RunnerOptions)
+	WithMaxWorkers(n int)
 	Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error)
 }
 
@@ -51,6 +55,12 @@ func WithClient(client client.Client) FunctionOption {
 	}
 }
 
+func WithCache(cache client.Reader) FunctionOption {
+	return func(r Function) {
+		r.WithCache(cache)
+	}
+}
+
 func WithFnMap(fnMap FuncMap) FunctionOption {
 	return func(r Function) {
 		r.WithFnMap(fnMap)
@@ -68,3 +78,15 @@ func WithServiceClients(sc map[schema.GroupVersionKind]svcclient.ServiceClient)
 		r.WithServiceClients(sc)
 	}
 }
+
+func WithRunnerOptions(opts fnruntime.RunnerOptions) FunctionOption {
+	return func(r Function) {
+		r.WithRunnerOptions(opts)
+	}
+}
+
+func WithMaxWorkers(n int) FunctionOption {
+	return func(r Function) {
+		r.WithMaxWorkers(n)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/itchyny/gojq"
@@ -37,6 +39,7 @@ type fnExecConfig struct {
 
 func (r *fnExecConfig) exec(ctx context.Context, fnconfig ctrlcfgv1.Function, i input.Input) (output.Output, error) {
 	var items []*item
+	var rangeValue *ctrlcfgv1.RangeValue
 	var isRange bool
 	var ok bool
 	var err error
@@ -44,18 +47,19 @@ func (r *fnExecConfig) exec(ctx context.Context, fnconfig ctrlcfgv1.Function, i
 		r.l.Info("execute block")
 		if fnconfig.Block.Range != nil {
 			r.l.Info("execute range", "value", fnconfig.Block.Range.Value)
-			items, err = runRange(fnconfig.Block.Range.Value, i)
+			items, err = runRange(ctx, fnconfig.Block.Range.Value, i)
 			if err != nil {
 				r.l.Error(err, "cannot run range")
 				return nil, err
 			}
 			r.l.Info("range", "items", items)
+			rangeValue = fnconfig.Block.Range
 			isRange = true
 		}
 		if fnconfig.Block.Condition != nil {
 			r.l.Info("execute condition", "expression", fnconfig.Block.Condition.Expression)
 			if exp := fnconfig.Block.Condition.Expression; exp != "" {
-				ok, err = runCondition(exp, i)
+				ok, err = runCondition(ctx, exp, i)
 				if err != nil {
 					r.l.Error(err, "cannot run range")
 					return nil, err
@@ -66,11 +70,12 @@ func (r *fnExecConfig) exec(ctx context.Context, fnconfig ctrlcfgv1.Function, i
 			}
 			if fnconfig.Block.Condition.Block.Range != nil {
 				r.l.Info("execute range in condition", "value", fnconfig.Block.Condition.Block.Range.Value)
-				items, err = runRange(fnconfig.Block.Condition.Block.Range.Value, i)
+				items, err = runRange(ctx, fnconfig.Block.Condition.Block.Range.Value, i)
 				if err != nil {
 					r.l.Error(err, "cannot run range in condition")
 					return nil, err
 				}
+				rangeValue = fnconfig.Block.Condition.Block.Range
 				isRange = true
 			}
 		}
@@ -82,40 +87,31 @@ func (r *fnExecConfig) exec(ctx context.Context, fnconfig ctrlcfgv1.Function, i
 	}
 	if numItems > 0 && isRange {
 		r.initOutputFn(numItems)
-		for n, item := range items {
-			fmt.Printf("range items: n: %d, item %#v\n", n, item)
-			// this is a protection to ensure we dont use the nil result in a range
-			if item.val != nil {
-				i.AddEntry("VALUE", item.val)
-				i.AddEntry("KEY", fmt.Sprint(n))
-				i.AddEntry("INDEX", n)
-
-				// resolve the local vars using jq and add them to the input
-				if err := resolveLocalVars(fnconfig, i); err != nil {
-					return nil, err
-				}
-
-				//i.Print("range")
-
-				if r.executeRange {
-					//extraInput := fec.prepareInputFn(fnconfig)
-					fi := r.filterInputFn(i)
-					x, err := r.runFn(ctx, fi)
-					if err != nil {
+		if r.executeRange {
+			if err := r.execRange(ctx, fnconfig, rangeValue, items, i); err != nil {
+				return nil, err
+			}
+		} else {
+			for n, item := range items {
+				fmt.Printf("range items: n: %d, item %#v\n", n, item)
+				// this is a protection to ensure we dont use the nil result in a range
+				if item.val != nil {
+					addRangeEntries(i, n, item)
+					// resolve the local vars using jq and add them to the input
+					if err := resolveLocalVars(ctx, fnconfig, i); err != nil {
 						return nil, err
 					}
-
-					// TODO add hook for service resolution
-					r.recordOutputFn(x)
 				}
 			}
 		}
 	}
-	if r.executeSingle {
+	// a function that executes the range items is not executed once more,
+	// since this would overwrite the output of the range
+	if r.executeSingle && !(isRange && r.executeRange) {
 		r.l.Info("execute single")
 		r.initOutputFn(1)
 		// resolve the local vars using jq and add them to the input
-		if err := resolveLocalVars(fnconfig, i); err != nil {
+		if err := resolveLocalVars(ctx, fnconfig, i); err != nil {
 			return nil, err
 		}
 		//extraInput := fec.prepareInputFn(fnconfig)
@@ -130,12 +126,240 @@ func (r *fnExecConfig) exec(ctx context.Context, fnconfig ctrlcfgv1.Function, i
 	return r.getFinalResultFn()
 }
 
+// execRange runs the function for every item of the range, up to the
+// concurrency of the range the items run in parallel. The output is recorded
+// in the order of the items, independent of the order in which the items
+// complete. With the failFast error policy the items that did not start yet
+// are not executed anymore after the first error and the running items are
+// cancelled, with the collect policy all items are executed and all the
+// errors are returned.
+func (r *fnExecConfig) execRange(ctx context.Context, fnconfig ctrlcfgv1.Function, rv *ctrlcfgv1.RangeValue, items []*item, i input.Input) error {
+	concurrency := rv.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	failFast := rv.ErrorPolicy != ctrlcfgv1.RangeErrorPolicyCollect
+
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outputs := make([]any, len(items))
+	errs := make([]error, len(items))
+	var firstErr error
+	var once sync.Once
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for n, it := range items {
+		// this is a protection to ensure we dont use the nil result in a range
+		if it.val == nil {
+			continue
+		}
+		sem <- struct{}{}
+		if rctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(n int, it *item) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.l.Info("execute range item", "index", n, "item", it.val)
+			outputs[n], errs[n] = r.runItem(rctx, fnconfig, n, it, i)
+			if errs[n] != nil {
+				once.Do(func() { firstErr = errs[n] })
+				if failFast {
+					cancel()
+				}
+			}
+		}(n, it)
+	}
+	wg.Wait()
+
+	if failFast && firstErr != nil {
+		return firstErr
+	}
+	var msgs []string
+	for n, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("item %d: %s", n, err.Error()))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%d of %d range items failed: %s", len(msgs), len(items), strings.Join(msgs, "; "))
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("range cancelled: %w", err)
+	}
+	for n, it := range items {
+		if it.val != nil {
+			// TODO add hook for service resolution
+			r.recordOutputFn(outputs[n])
+		}
+	}
+	return nil
+}
+
+// runItem runs the function for a single item of the range, every item
+// gets its own copy of the input since the items can run in parallel
+func (r *fnExecConfig) runItem(ctx context.Context, fnconfig ctrlcfgv1.Function, n int, it *item, i input.Input) (any, error) {
+	ii := input.New()
+	ii.Add(i)
+	addRangeEntries(ii, n, it)
+	// resolve the local vars using jq and add them to the input
+	if err := resolveLocalVars(ctx, fnconfig, ii); err != nil {
+		return nil, err
+	}
+	return r.runFn(ctx, r.filterInputFn(ii))
+}
+
+func addRangeEntries(i input.Input, n int, it *item) {
+	i.AddEntry("VALUE", it.val)
+	i.AddEntry("KEY", it.key)
+	i.AddEntry("INDEX", n)
+}
+
+// item is an item of a range, the key of an item is the key of the entry
+// when ranging over an object and the index of the item otherwise
 type item struct {
-	//key string
+	key string
 	val any
 }
 
-func runRange(exp string, i input.Input) ([]*item, error) {
+// runRange evaluates the range expression and returns the items of the range.
+// When the un-iterated expression evaluates to an object, e.g. $x or $x[]
+// with $x an object, the items are the entries of the object sorted by key.
+// Otherwise the items are the results of the expression.
+func runRange(ctx context.Context, exp string, i input.Input) ([]*item, error) {
+	q, err := gojq.Parse(exp)
+	if err != nil {
+		return nil, err
+	}
+	// iterating over an object only returns the values of the object, so the
+	// un-iterated expression is evaluated to get the keys
+	base := getUniteratedQuery(q)
+	if base == nil {
+		base = q
+	}
+	if !isIterating(base) {
+		values, err := evalRange(ctx, base, i)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 1 {
+			if o, ok := values[0].(map[string]any); ok {
+				return getObjectItems(o), nil
+			}
+		}
+	}
+	values, err := evalRange(ctx, q, i)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*item, 0, len(values))
+	for n, v := range values {
+		result = append(result, &item{key: fmt.Sprint(n), val: v})
+	}
+	return result, nil
+}
+
+// getUniteratedQuery returns the query without its trailing iteration, e.g.
+// $x for $x[] and $x | . for $x | .[], nil is returned when the query does
+// not end with an iteration
+func getUniteratedQuery(q *gojq.Query) *gojq.Query {
+	switch {
+	case q.Term != nil:
+		n := len(q.Term.SuffixList)
+		if n == 0 || !q.Term.SuffixList[n-1].Iter {
+			return nil
+		}
+		t := *q.Term
+		t.SuffixList = t.SuffixList[:n-1]
+		base := *q
+		base.Term = &t
+		return &base
+	case q.Op == gojq.OpPipe && q.Right != nil:
+		right := getUniteratedQuery(q.Right)
+		if right == nil {
+			return nil
+		}
+		base := *q
+		base.Right = right
+		return &base
+	}
+	return nil
+}
+
+// isIterating returns true when the query iterates, the results of such a
+// query are range items even when there is a single object
+func isIterating(q *gojq.Query) bool {
+	if q == nil {
+		return false
+	}
+	return isIterating(q.Left) || isIterating(q.Right) || isTermIterating(q.Term)
+}
+
+func isTermIterating(t *gojq.Term) bool {
+	if t == nil {
+		return false
+	}
+	// an array term is not walked as it collects the results of its query
+	if t.Type == gojq.TermTypeRecurse || t.Foreach != nil {
+		return true
+	}
+	for _, s := range t.SuffixList {
+		if s.Iter {
+			return true
+		}
+		if s.Index != nil && (isIterating(s.Index.Start) || isIterating(s.Index.End)) {
+			return true
+		}
+	}
+	if t.Func != nil {
+		for _, arg := range t.Func.Args {
+			if isIterating(arg) {
+				return true
+			}
+		}
+	}
+	if t.Unary != nil && isTermIterating(t.Unary.Term) {
+		return true
+	}
+	if t.If != nil {
+		if isIterating(t.If.Cond) || isIterating(t.If.Then) || isIterating(t.If.Else) {
+			return true
+		}
+		for _, elif := range t.If.Elif {
+			if isIterating(elif.Cond) || isIterating(elif.Then) {
+				return true
+			}
+		}
+	}
+	if t.Try != nil && isIterating(t.Try.Body) {
+		return true
+	}
+	return isIterating(t.Query)
+}
+
+// getObjectItems returns the entries of the object as range items, sorted by
+// key to get a stable order
+func getObjectItems(o map[string]any) []*item {
+	keys := make([]string, 0, len(o))
+	for k, v := range o {
+		if v == nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]*item, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, &item{key: k, val: o[k]})
+	}
+	return result
+}
+
+// evalRange returns the non nil results of the range query
+func evalRange(ctx context.Context, q *gojq.Query, i input.Input) ([]any, error) {
 	varNames := make([]string, 0, i.Length())
 	varValues := make([]any, 0, i.Length())
 	for name, v := range i.Get() {
@@ -146,16 +370,12 @@ func runRange(exp string, i input.Input) ([]*item, error) {
 	//fmt.Printf("runRange varNames: %v, varValues: %v\n", varNames, varValues)
 	//fmt.Printf("runRange exp: %s\n", exp)
 
-	q, err := gojq.Parse(exp)
-	if err != nil {
-		return nil, err
-	}
 	code, err := gojq.Compile(q, gojq.WithVariables(varNames))
 	if err != nil {
 		return nil, err
 	}
-	result := make([]*item, 0)
-	iter := code.Run(nil, varValues...)
+	result := make([]any, 0)
+	iter := code.RunWithContext(ctx, nil, varValues...)
 	for {
 		v, ok := iter.Next()
 		if !ok {
@@ -174,13 +394,13 @@ func runRange(exp string, i input.Input) ([]*item, error) {
 			continue
 		}
 		fmt.Printf("runRange result item: %#v\n", v)
-		result = append(result, &item{val: v})
+		result = append(result, v)
 	}
 
 	return result, nil
 }
 
-func runCondition(exp string, i input.Input) (bool, error) {
+func runCondition(ctx context.Context, exp string, i input.Input) (bool, error) {
 	varNames := make([]string, 0, i.Length())
 	varValues := make([]any, 0, i.Length())
 	for name, v := range i.Get() {
@@ -196,7 +416,7 @@ func runCondition(exp string, i input.Input) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	iter := code.Run(nil, varValues...)
+	iter := code.RunWithContext(ctx, nil, varValues...)
 
 	v, ok := iter.Next()
 	if !ok {
@@ -214,7 +434,7 @@ func runCondition(exp string, i input.Input) (bool, error) {
 	return false, fmt.Errorf("unexpected result type, want bool got %T", v)
 }
 
-func resolveLocalVars(fnconfig ctrlcfgv1.Function, i input.Input) error {
+func resolveLocalVars(ctx context.Context, fnconfig ctrlcfgv1.Function, i input.Input) error {
 	if fnconfig.Vars != nil {
 		for varName, expression := range fnconfig.Vars {
 			// We are lazy and provide all reference input to JQ
@@ -229,7 +449,7 @@ func resolveLocalVars(fnconfig ctrlcfgv1.Function, i input.Input) error {
 			//	}
 			//fmt.Printf("resolveLocalVars varname: %s expression %s\n", varName, expression)
 
-			v, err := runJQ(expression, i)
+			v, err := runJQ(ctx, expression, i)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,137 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/exec/input"
+)
+
+// rangeRun records the range items that ran and the output of the range
+type rangeRun struct {
+	m       sync.Mutex
+	ran     []string
+	outputs []any
+}
+
+// rangeItem returns a range item that takes delay to run and that fails
+// when fail is set
+func rangeItem(name string, delay time.Duration, fail bool) *item {
+	return &item{
+		val: map[string]any{"name": name, "delay": delay, "fail": fail},
+	}
+}
+
+// execRange runs the range items with the concurrency and error policy
+func execRange(ctx context.Context, concurrency int, policy ctrlcfgv1.RangeErrorPolicy, items []*item) (*rangeRun, error) {
+	rr := &rangeRun{ran: []string{}, outputs: []any{}}
+	r := &fnExecConfig{
+		filterInputFn: func(i input.Input) input.Input { return i },
+		runFn: func(ctx context.Context, i input.Input) (any, error) {
+			v := i.GetValue("VALUE").(map[string]any)
+			select {
+			case <-time.After(v["delay"].(time.Duration)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			rr.m.Lock()
+			rr.ran = append(rr.ran, v["name"].(string))
+			rr.m.Unlock()
+			if v["fail"].(bool) {
+				return nil, fmt.Errorf("%s failed", v["name"])
+			}
+			return v["name"], nil
+		},
+		recordOutputFn: func(o any) { rr.outputs = append(rr.outputs, o) },
+		l:              logr.Discard(),
+	}
+	err := r.execRange(ctx, ctrlcfgv1.Function{}, &ctrlcfgv1.RangeValue{
+		Concurrency: concurrency,
+		ErrorPolicy: policy,
+	}, items, input.New())
+	return rr, err
+}
+
+var _ = Describe("Functions", func() {
+	Describe("execRange", func() {
+		It("should run the items sequentially by default", func() {
+			rr, err := execRange(context.Background(), 0, ctrlcfgv1.RangeErrorPolicyFailFast, []*item{
+				rangeItem("a", 0, false),
+				rangeItem("b", 0, false),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rr.ran).To(Equal([]string{"a", "b"}))
+			Expect(rr.outputs).To(Equal([]any{"a", "b"}))
+		})
+
+		It("should record the output of parallel items in the order of the items", func() {
+			rr, err := execRange(context.Background(), 3, ctrlcfgv1.RangeErrorPolicyFailFast, []*item{
+				rangeItem("a", 30*time.Millisecond, false),
+				rangeItem("b", 20*time.Millisecond, false),
+				rangeItem("c", 0, false),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rr.ran).To(ConsistOf("a", "b", "c"))
+			Expect(rr.outputs).To(Equal([]any{"a", "b", "c"}))
+		})
+
+		It("should not run the nil items", func() {
+			rr, err := execRange(context.Background(), 1, ctrlcfgv1.RangeErrorPolicyFailFast, []*item{
+				rangeItem("a", 0, false),
+				{},
+				rangeItem("c", 0, false),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rr.ran).To(Equal([]string{"a", "c"}))
+			Expect(rr.outputs).To(Equal([]any{"a", "c"}))
+		})
+
+		It("should not run the items after the first error by default", func() {
+			rr, err := execRange(context.Background(), 1, "", []*item{
+				rangeItem("a", 0, false),
+				rangeItem("b", 0, true),
+				rangeItem("c", 0, false),
+			})
+			Expect(err).To(MatchError("b failed"))
+			Expect(rr.ran).To(Equal([]string{"a", "b"}))
+			Expect(rr.outputs).To(BeEmpty())
+		})
+
+		It("should cancel the running items with the failFast policy", func() {
+			rr, err := execRange(context.Background(), 2, ctrlcfgv1.RangeErrorPolicyFailFast, []*item{
+				rangeItem("a", time.Minute, false),
+				rangeItem("b", 0, true),
+			})
+			Expect(err).To(MatchError("b failed"))
+			Expect(rr.ran).To(Equal([]string{"b"}))
+			Expect(rr.outputs).To(BeEmpty())
+		})
+
+		It("should run all the items and return all the errors with the collect policy", func() {
+			rr, err := execRange(context.Background(), 1, ctrlcfgv1.RangeErrorPolicyCollect, []*item{
+				rangeItem("a", 0, true),
+				rangeItem("b", 0, false),
+				rangeItem("c", 0, true),
+			})
+			Expect(err).To(MatchError("2 of 3 range items failed: item 0: a failed; item 2: c failed"))
+			Expect(rr.ran).To(Equal([]string{"a", "b", "c"}))
+			Expect(rr.outputs).To(BeEmpty())
+		})
+
+		It("should not record the output when the range is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			rr, err := execRange(ctx, 1, ctrlcfgv1.RangeErrorPolicyFailFast, []*item{
+				rangeItem("a", 0, false),
+			})
+			Expect(err).To(MatchError(ContainSubstring("range cancelled")))
+			Expect(rr.outputs).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package functions
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/exec/input"
+)
+
+// getRangeItems returns the range items of the expression with the value
+// as $x
+func getRangeItems(exp string, v any) []*item {
+	i := input.New()
+	i.AddEntry("x", v)
+	items, err := runRange(context.Background(), exp, i)
+	Expect(err).NotTo(HaveOccurred())
+	return items
+}
+
+var _ = Describe("Functions", func() {
+	Describe("runRange", func() {
+		a := map[string]any{"name": "a", "spec": "x"}
+		b := map[string]any{"name": "b", "spec": "y"}
+
+		It("should return the index as key of a list of one object", func() {
+			Expect(getRangeItems("$x[]", []any{a})).To(Equal([]*item{
+				{key: "0", val: a},
+			}))
+		})
+
+		It("should return the index as key of a list of several objects", func() {
+			Expect(getRangeItems("$x[]", []any{a, b})).To(Equal([]*item{
+				{key: "0", val: a},
+				{key: "1", val: b},
+			}))
+		})
+
+		It("should return the object selected by a pipe from a list", func() {
+			Expect(getRangeItems(`$x | .[] | select(.name == "b")`, []any{a, b})).To(Equal([]*item{
+				{key: "0", val: b},
+			}))
+		})
+
+		It("should return the entries of an object with the entry key as key", func() {
+			Expect(getRangeItems("$x", a)).To(Equal([]*item{
+				{key: "name", val: "a"},
+				{key: "spec", val: "x"},
+			}))
+		})
+
+		It("should return the entries of an iterated object", func() {
+			Expect(getRangeItems("$x[]", a)).To(Equal([]*item{
+				{key: "name", val: "a"},
+				{key: "spec", val: "x"},
+			}))
+		})
+
+		It("should return the entries of a piped iterated object", func() {
+			Expect(getRangeItems("$x | .[]", a)).To(Equal([]*item{
+				{key: "name", val: "a"},
+				{key: "spec", val: "x"},
+			}))
+		})
+
+		It("should return the entries of an object nested in an object", func() {
+			Expect(getRangeItems("$x.spec[]", map[string]any{"spec": a})).To(Equal([]*item{
+				{key: "name", val: "a"},
+				{key: "spec", val: "x"},
+			}))
+		})
+
+		It("should return the index as key of a list of scalars", func() {
+			Expect(getRangeItems("$x[]", []any{"a", "b"})).To(Equal([]*item{
+				{key: "0", val: "a"},
+				{key: "1", val: "b"},
+			}))
+		})
+
+		It("should return no items for a null value", func() {
+			Expect(getRangeItems("$x[]", nil)).To(BeEmpty())
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,7 +10,7 @@ import (
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 )
 
-func runJQ(exp string, i input.Input) (any, error) {
+func runJQ(ctx context.Context, exp string, i input.Input) (any, error) {
 	if exp == "" {
 		return nil, errors.New("missing input value")
 	}
@@ -34,7 +35,7 @@ func runJQ(exp string, i input.Input) (any, error) {
 	}
 
 	result := make([]any, 0)
-	iter := code.Run(nil, varValues...)
+	iter := code.RunWithContext(ctx, nil, varValues...)
 	for {
 		v, ok := iter.Next()
 		if !ok { // should this not be later
@@ -55,8 +56,8 @@ func runJQ(exp string, i input.Input) (any, error) {
 	return result, nil
 }
 
-func runJQOnce(code *gojq.Code, input any, vars ...any) (any, error) {
-	iter := code.Run(input, vars...)
+func runJQOnce(ctx context.Context, code *gojq.Code, input any, vars ...any) (any, error) {
+	iter := code.RunWithContext(ctx, input, vars...)
 
 	v, ok := iter.Next()
 	if !ok {
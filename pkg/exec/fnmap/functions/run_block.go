@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
 	"github.com/yndd/lcnc-runtime/pkg/exec/exechandler"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -26,7 +28,7 @@ func NewBlockFn() fnmap.Function {
 	}
 
 	r.fec = &fnExecConfig{
-		executeRange:  false,
+		executeRange:  true,
 		executeSingle: true,
 		// execution functions
 		filterInputFn: r.filterInput,
@@ -44,11 +46,15 @@ type block struct {
 	// fec exec config
 	fec *fnExecConfig
 	// init config
-	curOutputs output.Output // this is the current output list
-	curResults result.Result
-	fnMap      fnmap.FuncMap
+	curOutputs     output.Output // this is the current output list
+	curResults     result.Result
+	fnMap          fnmap.FuncMap
+	rootVertexName string
+	maxWorkers     int
 	// runtime config
-	d rtdag.RuntimeDAG
+	d       rtdag.RuntimeDAG
+	vars    map[string]string
+	isRange bool
 	// result, output
 	m      sync.RWMutex
 	output []any
@@ -72,21 +78,33 @@ func (r *block) WithResult(result result.Result) {
 
 func (r *block) WithNameAndNamespace(name, namespace string) {}
 
-func (r *block) WithRootVertexName(name string) {}
+func (r *block) WithRootVertexName(name string) {
+	r.rootVertexName = name
+}
 
 func (r *block) WithClient(client client.Client) {}
 
+func (r *block) WithCache(cache client.Reader) {}
+
 func (r *block) WithFnMap(fnMap fnmap.FuncMap) {
 	r.fnMap = fnMap
 }
 
 func (r *block) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *block) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *block) WithMaxWorkers(n int) {
+	r.maxWorkers = n
+}
+
 func (r *block) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get())
 	// Here we prepare the input we get from the runtime
 	// e.g. DAG, outputs/outputInfo (internal/GVK/etc), fnConfig parameters, etc etc
 	r.d = vertexContext.BlockDAG
+	r.vars = vertexContext.Function.Vars
+	r.isRange = vertexContext.Function.Block.HasRange()
 
 	// execute to function
 	return r.fec.exec(ctx, vertexContext.Function, i)
@@ -102,11 +120,65 @@ func (r *block) recordOutput(o any) {
 	r.output = append(r.output, o)
 }
 
+// getFinalResult returns the outputs of the functions within the block, for
+// a range the outputs of the iterations are aggregated
 func (r *block) getFinalResult() (output.Output, error) {
 	if len(r.output) == 0 {
 		return output.New(), nil
 	}
-	return output.New(), nil
+	if r.isRange {
+		return aggregateOutputs(r.output)
+	}
+	o, ok := r.output[0].(output.Output)
+	if !ok {
+		err := fmt.Errorf("expecting output, got %T", r.output[0])
+		r.l.Error(err, "cannot get final result")
+		return nil, err
+	}
+	return o, nil
+}
+
+// aggregateOutputs aggregates the outputs of the iterations of a range in
+// list valued variables, in the order of the iterations. The resources of an
+// external output are concatenated such that the variable holds the resources
+// of all the iterations, the variable of an internal output holds the value of
+// every iteration that produced it.
+func aggregateOutputs(outputs []any) (output.Output, error) {
+	aggregated := map[string]*output.OutputInfo{}
+	for _, x := range outputs {
+		o, ok := x.(output.Output)
+		if !ok {
+			return nil, fmt.Errorf("expecting output, got %T", x)
+		}
+		for varName, v := range o.Get() {
+			oi, ok := v.(*output.OutputInfo)
+			if !ok {
+				return nil, fmt.Errorf("expecting outputInfo, got %T", v)
+			}
+			a, ok := aggregated[varName]
+			if !ok {
+				a = &output.OutputInfo{
+					Internal:       oi.Internal,
+					Conditioned:    oi.Conditioned,
+					GVK:            oi.GVK,
+					ForceConflicts: oi.ForceConflicts,
+					Data:           []any{},
+				}
+				aggregated[varName] = a
+			}
+			data := a.Data.([]any)
+			if d, ok := oi.Data.([]any); ok && !oi.Internal {
+				a.Data = append(data, d...)
+			} else {
+				a.Data = append(data, oi.Data)
+			}
+		}
+	}
+	o := output.New()
+	for varName, oi := range aggregated {
+		o.AddEntry(varName, oi)
+	}
+	return o, nil
 }
 
 func (r *block) filterInput(i input.Input) input.Input { return i }
@@ -126,24 +198,78 @@ func (r *block) run(ctx context.Context, i input.Input) (any, error) {
 
 	rootVertexName := r.d.GetRootVertex()
 
+	// the local variables of the block are resolved in the input and are
+	// only visible to the functions within the block DAG, including the
+	// blocks that are nested within the block
+	vars := make(map[string]any, len(r.vars))
+	for varName := range r.vars {
+		vars[varName] = i.GetValue(varName)
+	}
+	// the outputs of the functions within the block are kept in a local
+	// output, such that the iterations of a range do not overwrite each other
+	lo := output.New()
+	o := output.NewScope(r.curOutputs, lo, vars)
+
+	// every iteration of a range records the results of the functions in its
+	// own nested result
+	name := rootVertexName
+	res := r.curResults
+	if r.isRange {
+		name = fmt.Sprintf("%s[%v]", rootVertexName, i.GetValue("KEY"))
+		res = result.New()
+	}
+
 	// initialize the handler
 	h := exechandler.New(&exechandler.Config{
-		Name:   rootVertexName,
-		Type:   result.ExecBlockType,
-		DAG:    r.d,
-		FnMap:  r.fnMap,
-		Output: r.curOutputs,
-		Result: r.curResults,
+		Name:           name,
+		RootVertexName: r.rootVertexName,
+		Type:           result.ExecBlockType,
+		DAG:            r.d,
+		FnMap:          r.fnMap.WithOutput(o),
+		Output:         o,
+		Result:         res,
 	})
 
+	var start, finish time.Time
+	success := false
 	e := executor.New(r.d, &executor.Config{
-		Name:               rootVertexName,
+		Name:               name,
 		From:               rootVertexName,
+		MaxWorkers:         r.maxWorkers,
 		VertexFuntionRunFn: h.FunctionRun,
-		ExecPostRunFn:      h.RecordFinalResult,
+		VertexSkipFn:       h.RecordSkip,
+		ExecPostRunFn: func(s, f time.Time, ok bool) {
+			start, finish, success = s, f, ok
+			h.RecordFinalResult(s, f, ok)
+		},
 	})
 	e.Run(ctx)
 
-	return nil, nil
-
+	var err error
+	if !success {
+		err = fmt.Errorf("block %s failed", name)
+	}
+	if r.isRange {
+		ri := &result.ResultInfo{
+			Type:        result.ExecBlockType,
+			ExecName:    rootVertexName,
+			VertexName:  name,
+			StartTime:   start,
+			EndTime:     finish,
+			Input:       i,
+			Output:      lo,
+			Outcome:     executor.VertexSucceeded,
+			Success:     success,
+			BlockResult: res,
+		}
+		if err != nil {
+			ri.Outcome = executor.VertexFailed
+			ri.Reason = err.Error()
+		}
+		r.curResults.Add(ri)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lo, nil
 }
@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/input"
+	"github.com/yndd/lcnc-runtime/pkg/exec/output"
+	"github.com/yndd/lcnc-runtime/pkg/exec/result"
+	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newBlockVertexContext returns a block with a jq function that appends a
+// suffix to the local variable item of the block, the block ranges over
+// the range value when supplied
+func newBlockVertexContext(item, rangeValue string) *rtdag.VertexContext {
+	d := rtdag.New()
+	Expect(d.AddVertex("blockRoot", &rtdag.VertexContext{
+		VertexName: "blockRoot",
+		Kind:       rtdag.RootVertexKind,
+		Function:   ctrlcfgv1.Function{Type: ctrlcfgv1.RootType},
+		Outputs:    output.New(),
+	})).To(Succeed())
+	outputs := output.New()
+	outputs.AddEntry("name", &output.OutputInfo{Internal: true})
+	Expect(d.AddVertex("suffix", &rtdag.VertexContext{
+		VertexName: "suffix",
+		Kind:       rtdag.FunctionVertexKind,
+		Function: ctrlcfgv1.Function{
+			Type:  ctrlcfgv1.JQType,
+			Input: &ctrlcfgv1.Input{Expression: `$item[] + "-x"`},
+		},
+		References: []string{"item"},
+		Outputs:    outputs,
+	})).To(Succeed())
+	d.Connect("blockRoot", "suffix")
+
+	fn := ctrlcfgv1.Function{
+		Type: ctrlcfgv1.BlockType,
+		Vars: map[string]string{"item": item},
+	}
+	if rangeValue != "" {
+		fn.Block.Range = &ctrlcfgv1.RangeValue{Value: rangeValue}
+	}
+	return &rtdag.VertexContext{
+		VertexName: "block",
+		Kind:       rtdag.FunctionVertexKind,
+		Function:   fn,
+		BlockDAG:   d,
+	}
+}
+
+// getVertexNames returns the vertex names of the results
+func getVertexNames(res result.Result) []string {
+	vertexNames := []string{}
+	for _, v := range res.Get() {
+		ri, ok := v.(*result.ResultInfo)
+		Expect(ok).To(BeTrue())
+		vertexNames = append(vertexNames, ri.VertexName)
+	}
+	return vertexNames
+}
+
+var _ = Describe("Functions", func() {
+	Describe("block", func() {
+		It("should record the results of the functions in the block result", func() {
+			res := result.New()
+			fm := Init(&fnmap.Config{
+				Output: output.New(),
+				Result: res,
+			})
+			o, err := fm.Run(context.Background(), newBlockVertexContext(`"a"`, ""), input.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(o.GetData("name")).To(Equal([]any{"a-x"}))
+			Expect(getVertexNames(res)).To(ConsistOf("blockRoot", "suffix", "total"))
+		})
+
+		It("should record the results of every range iteration in a nested result", func() {
+			res := result.New()
+			fm := Init(&fnmap.Config{
+				Output: output.New(),
+				Result: res,
+			})
+			i := input.New()
+			i.AddEntry("items", []any{"a", "b"})
+			_, err := fm.Run(context.Background(), newBlockVertexContext("$VALUE", "$items[]"), i)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getVertexNames(res)).To(Equal([]string{"blockRoot[0]", "blockRoot[1]"}))
+			for _, v := range res.Get() {
+				ri := v.(*result.ResultInfo)
+				Expect(ri.Type).To(Equal(result.ExecBlockType))
+				Expect(ri.ExecName).To(Equal("blockRoot"))
+				Expect(ri.Success).To(BeTrue())
+				Expect(getVertexNames(ri.BlockResult)).To(ConsistOf("blockRoot", "suffix", "total"))
+			}
+		})
+
+		It("should aggregate the outputs of the range iterations", func() {
+			fm := Init(&fnmap.Config{
+				Output: output.New(),
+				Result: result.New(),
+			})
+			i := input.New()
+			i.AddEntry("items", []any{"a", "b"})
+			o, err := fm.Run(context.Background(), newBlockVertexContext("$VALUE", "$items[]"), i)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(o.GetData("name")).To(Equal([]any{[]any{"a-x"}, []any{"b-x"}}))
+		})
+	})
+
+	Describe("aggregateOutputs", func() {
+		gvk := &schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+		It("should concatenate the resources of an external output", func() {
+			o1 := output.New()
+			o1.AddEntry("pods", &output.OutputInfo{GVK: gvk, Data: []any{"pod1"}})
+			o2 := output.New()
+			o2.AddEntry("pods", &output.OutputInfo{GVK: gvk, Data: []any{"pod2", "pod3"}})
+			o, err := aggregateOutputs([]any{o1, o2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(o.GetValue("pods")).To(Equal(&output.OutputInfo{
+				GVK:  gvk,
+				Data: []any{"pod1", "pod2", "pod3"},
+			}))
+		})
+
+		It("should collect the values of an internal output per iteration", func() {
+			o1 := output.New()
+			o1.AddEntry("name", &output.OutputInfo{Internal: true, Data: "a"})
+			o2 := output.New()
+			o3 := output.New()
+			o3.AddEntry("name", &output.OutputInfo{Internal: true, Data: "c"})
+			o, err := aggregateOutputs([]any{o1, o2, o3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(o.GetValue("name")).To(Equal(&output.OutputInfo{
+				Internal: true,
+				Data:     []any{"a", "c"},
+			}))
+		})
+
+		It("should fail when an iteration did not provide an output", func() {
+			_, err := aggregateOutputs([]any{"a"})
+			Expect(err).To(MatchError("expecting output, got string"))
+		})
+	})
+})
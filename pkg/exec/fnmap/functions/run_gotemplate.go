@@ -12,6 +12,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -72,10 +73,16 @@ func (r *gt) WithRootVertexName(name string) {}
 
 func (r *gt) WithClient(client client.Client) {}
 
+func (r *gt) WithCache(cache client.Reader) {}
+
 func (r *gt) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *gt) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *gt) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *gt) WithMaxWorkers(n int) {}
+
 func (r *gt) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get(), "resource", vertexContext.Function.Input.Resource.Raw)
 
@@ -112,9 +119,10 @@ func (r *gt) getFinalResult() (output.Output, error) {
 			return o, err
 		}
 		o.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     r.output,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           r.output,
 		})
 	}
 	return o, nil
@@ -60,6 +60,7 @@ type image struct {
 	gvkToVarName map[string]string
 	// result, output
 	serviceClients map[schema.GroupVersionKind]svcclient.ServiceClient
+	runnerOpts     fnruntime.RunnerOptions
 	m              sync.RWMutex
 	output         output.Output
 	numItems       int
@@ -89,12 +90,20 @@ func (r *image) WithRootVertexName(name string) {
 
 func (r *image) WithClient(client client.Client) {}
 
+func (r *image) WithCache(cache client.Reader) {}
+
 func (r *image) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *image) WithServiceClients(sc map[schema.GroupVersionKind]svcclient.ServiceClient) {
 	r.serviceClients = sc
 }
 
+func (r *image) WithRunnerOptions(opts fnruntime.RunnerOptions) {
+	r.runnerOpts = opts
+}
+
+func (r *image) WithMaxWorkers(n int) {}
+
 func (r *image) initOutput(numItems int) {
 	r.output = output.New()
 	r.numItems = numItems
@@ -116,11 +125,11 @@ func (r *image) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i i
 // run is an instance run of the function, if this is executed in a block
 // this is executed multiple time, once per block
 func (r *image) run(ctx context.Context, i input.Input) (any, error) {
-	runner, err := fnruntime.NewRunner(ctx, r.fnconfig,
-		fnruntime.RunnerOptions{
-			ResolveToImage: fnruntime.ResolveToImageForCLI,
-		},
-	)
+	opts := r.runnerOpts
+	if opts.ResolveToImage == nil {
+		opts.ResolveToImage = fnruntime.ResolveToImageForCLI
+	}
+	runner, err := fnruntime.NewRunner(ctx, r.fnconfig, opts)
 	if err != nil {
 		r.l.Error(err, "cannot get runner")
 		return nil, err
@@ -219,9 +228,10 @@ func (r *image) recordOutput(o any) {
 			break
 		}
 		r.output.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     krmOutput,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           krmOutput,
 		})
 	}
 }
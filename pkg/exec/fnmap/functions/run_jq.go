@@ -7,6 +7,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -66,10 +67,16 @@ func (r *jq) WithRootVertexName(name string) {}
 
 func (r *jq) WithClient(client client.Client) {}
 
+func (r *jq) WithCache(cache client.Reader) {}
+
 func (r *jq) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *jq) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *jq) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *jq) WithMaxWorkers(n int) {}
+
 func (r *jq) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get(), "expression", vertexContext.Function.Input.Expression)
 
@@ -99,9 +106,10 @@ func (r *jq) getFinalResult() (output.Output, error) {
 			return o, err
 		}
 		o.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     r.output,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           r.output,
 		})
 	}
 	return o, nil
@@ -110,5 +118,5 @@ func (r *jq) getFinalResult() (output.Output, error) {
 func (r *jq) filterInput(i input.Input) input.Input { return i }
 
 func (r *jq) run(ctx context.Context, i input.Input) (any, error) {
-	return runJQ(r.expression, i)
+	return runJQ(ctx, r.expression, i)
 }
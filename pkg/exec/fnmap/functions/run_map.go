@@ -10,6 +10,7 @@ import (
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/itchyny/gojq"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -82,10 +83,16 @@ func (r *kv) WithRootVertexName(name string) {}
 
 func (r *kv) WithClient(client client.Client) {}
 
+func (r *kv) WithCache(cache client.Reader) {}
+
 func (r *kv) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *kv) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *kv) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *kv) WithMaxWorkers(n int) {}
+
 func (r *kv) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get(), "key", vertexContext.Function.Input.Key, "value", vertexContext.Function.Input.Value)
 
@@ -125,9 +132,10 @@ func (r *kv) getFinalResult() (output.Output, error) {
 			return o, err
 		}
 		o.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     r.output,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           r.output,
 		})
 	}
 	return o, nil
@@ -183,13 +191,13 @@ func (r *kv) run(ctx context.Context, i input.Input) (any, error) {
 		return nil, err
 	}
 
-	v, err := runJQOnce(valC, nil, varValues...)
+	v, err := runJQOnce(ctx, valC, nil, varValues...)
 	if err != nil {
 		r.l.Error(err, "cannot buildKV runJQOnce valC")
 		return nil, err
 	}
 
-	k, err := runJQOnce(keyC, nil, varValues...)
+	k, err := runJQOnce(ctx, keyC, nil, varValues...)
 	if err != nil {
 		r.l.Error(err, "cannot buildKV runJQOnce keyC")
 		return nil, err
@@ -8,19 +8,26 @@ import (
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
 	"github.com/yndd/lcnc-runtime/pkg/exec/rtdag"
 	"github.com/yndd/lcnc-runtime/pkg/meta"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/yaml"
 )
 
+// listPageSize is the number of objects that is read per page by a live query
+const listPageSize = 500
+
 func NewQueryFn() fnmap.Function {
 	l := ctrl.Log.WithName("query fn")
 	r := &query{
@@ -48,10 +55,16 @@ type query struct {
 	fec *fnExecConfig
 	// init config
 	client client.Client
+	cache  client.Reader
 	// runtime config
-	outputs  output.Output
-	resource runtime.RawExtension
-	selector *metav1.LabelSelector
+	outputs       output.Output
+	resource      runtime.RawExtension
+	selector      *metav1.LabelSelector
+	fieldSelector map[string]string
+	namespace     string
+	get           *ctrlcfgv1.QueryGet
+	ownedBy       string
+	live          bool
 	// output, output
 	output any
 	// logging
@@ -76,17 +89,30 @@ func (r *query) WithClient(client client.Client) {
 	r.client = client
 }
 
+func (r *query) WithCache(cache client.Reader) {
+	r.cache = cache
+}
+
 func (r *query) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *query) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *query) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *query) WithMaxWorkers(n int) {}
+
 func (r *query) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get(), "resource", vertexContext.Function.Input.Resource)
 	// Here we prepare the input we get from the runtime
 	// e.g. DAG, outputs/outputInfo (internal/GVK/etc), fnConfig parameters, etc etc
 	r.outputs = vertexContext.Outputs
 	r.resource = vertexContext.Function.Input.Resource
-	//r.selector = vertexContext.Function.Input.Selector
+	r.selector = vertexContext.Function.Input.Selector
+	r.fieldSelector = vertexContext.Function.Input.FieldSelector
+	r.namespace = vertexContext.Function.Input.Namespace
+	r.get = vertexContext.Function.Input.Get
+	r.ownedBy = vertexContext.Function.Input.OwnedBy
+	r.live = vertexContext.Function.Input.Live
 
 	// execute to function
 	return r.fec.exec(ctx, vertexContext.Function, i)
@@ -108,9 +134,10 @@ func (r *query) getFinalResult() (output.Output, error) {
 			return o, err
 		}
 		o.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     r.output,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           r.output,
 		})
 	}
 	//o.Print()
@@ -127,34 +154,204 @@ func (r *query) run(ctx context.Context, i input.Input) (any, error) {
 	}
 	r.l.Info("query run", "gvk", gvk)
 
-	opts := []client.ListOption{}
-	if r.selector != nil {
-		// TODO namespace
-		//opts = append(opts, client.InNamespace("x"))
-		opts = append(opts, client.MatchingLabels(r.selector.MatchLabels))
+	if r.get != nil {
+		return r.getObject(ctx, gvk, i)
 	}
 
-	o := meta.GetUnstructuredListFromGVK(gvk)
-	if err := r.client.List(ctx, o, opts...); err != nil {
+	opts, err := r.getListOptions(ctx, i)
+	if err != nil {
+		r.l.Error(err, "cannot get list options")
+		return nil, err
+	}
+
+	var ownerUID types.UID
+	if r.ownedBy != "" {
+		ownerUID, err = getOwnerUID(ctx, r.ownedBy, i)
+		if err != nil {
+			r.l.Error(err, "cannot get owner")
+			return nil, err
+		}
+	}
+
+	items, err := r.list(ctx, gvk, opts)
+	if err != nil {
 		r.l.Error(err, "cannot list gvk", "gvk", gvk, "options", opts)
 		return nil, err
 	}
 
-	rj := make([]interface{}, 0, len(o.Items))
-	for _, v := range o.Items {
-		b, err := yaml.Marshal(v.UnstructuredContent())
+	rj := make([]interface{}, 0, len(items))
+	for _, v := range items {
+		if r.ownedBy != "" {
+			// only the objects controlled by the owner are returned
+			ref := metav1.GetControllerOf(&v)
+			if ref == nil || ref.UID != ownerUID {
+				continue
+			}
+		}
+		rj = append(rj, runtime.DeepCopyJSON(v.UnstructuredContent()))
+	}
+
+	return rj, nil
+}
+
+// isLive returns true when the query reads from the api server instead of
+// the informer cache, the cache only supports field selectors on indexed
+// fields so queries with a field selector are read live
+func (r *query) isLive() bool {
+	return r.cache == nil || r.live || len(r.fieldSelector) != 0
+}
+
+// list returns the objects of the query, the informer of the gvk is started
+// by the cache on the first read. Live lists are read in pages.
+func (r *query) list(ctx context.Context, gvk *schema.GroupVersionKind, opts []client.ListOption) ([]unstructured.Unstructured, error) {
+	if !r.isLive() {
+		o := meta.GetUnstructuredListFromGVK(gvk)
+		if err := r.cache.List(ctx, o, opts...); err != nil {
+			return nil, err
+		}
+		return o.Items, nil
+	}
+
+	items := []unstructured.Unstructured{}
+	cont := ""
+	for {
+		o := meta.GetUnstructuredListFromGVK(gvk)
+		if err := r.client.List(ctx, o, append(opts, client.Limit(listPageSize), client.Continue(cont))...); err != nil {
+			return nil, err
+		}
+		items = append(items, o.Items...)
+		cont = o.GetContinue()
+		if cont == "" {
+			return items, nil
+		}
+	}
+}
+
+// getObject returns the object of the query with the name of the get option,
+// when the object is not found the result is nil unless failIfNotFound is set
+func (r *query) getObject(ctx context.Context, gvk *schema.GroupVersionKind, i input.Input) (any, error) {
+	name, err := resolveSelectorValue(ctx, r.get.Name, i)
+	if err != nil {
+		r.l.Error(err, "cannot resolve name")
+		return nil, err
+	}
+	nsn := types.NamespacedName{Name: name}
+	if r.namespace != "" {
+		nsn.Namespace, err = resolveSelectorValue(ctx, r.namespace, i)
 		if err != nil {
-			r.l.Error(err, "cannot marshal data")
+			r.l.Error(err, "cannot resolve namespace")
 			return nil, err
 		}
+	}
+
+	var reader client.Reader = r.client
+	if !r.isLive() {
+		reader = r.cache
+	}
+	o := meta.GetUnstructuredFromGVK(gvk)
+	if err := reader.Get(ctx, nsn, o); err != nil {
+		if apierrors.IsNotFound(err) && !r.get.FailIfNotFound {
+			r.l.Info("object not found", "gvk", gvk, "nsn", nsn)
+			return nil, nil
+		}
+		r.l.Error(err, "cannot get object", "gvk", gvk, "nsn", nsn)
+		return nil, err
+	}
+	return runtime.DeepCopyJSON(o.UnstructuredContent()), nil
+}
 
-		vrj := map[string]interface{}{}
-		if err := yaml.Unmarshal(b, &vrj); err != nil {
-			r.l.Error(err, "cannot unmarshal data")
+// getListOptions returns the namespace and the label and field selectors of
+// the query as list options, the jq expressions are resolved with the input
+func (r *query) getListOptions(ctx context.Context, i input.Input) ([]client.ListOption, error) {
+	opts := []client.ListOption{}
+	if r.namespace != "" {
+		ns, err := resolveSelectorValue(ctx, r.namespace, i)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve namespace: %w", err)
+		}
+		opts = append(opts, client.InNamespace(ns))
+	}
+	if r.selector != nil {
+		ls := r.selector.DeepCopy()
+		for k, v := range ls.MatchLabels {
+			val, err := resolveSelectorValue(ctx, v, i)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve label %s: %w", k, err)
+			}
+			ls.MatchLabels[k] = val
+		}
+		sel, err := metav1.LabelSelectorAsSelector(ls)
+		if err != nil {
 			return nil, err
 		}
-		rj = append(rj, vrj)
+		opts = append(opts, client.MatchingLabelsSelector{Selector: sel})
 	}
+	if len(r.fieldSelector) != 0 {
+		set := fields.Set{}
+		for k, v := range r.fieldSelector {
+			val, err := resolveSelectorValue(ctx, v, i)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve field %s: %w", k, err)
+			}
+			set[k] = val
+		}
+		opts = append(opts, client.MatchingFieldsSelector{Selector: fields.SelectorFromSet(set)})
+	}
+	return opts, nil
+}
 
-	return rj, nil
+// resolveSelectorValue returns the value of a jq expression or the literal
+// value, the expression must result in a scalar
+func resolveSelectorValue(ctx context.Context, s string, i input.Input) (string, error) {
+	if !ctrlcfgv1.IsExpression(s) {
+		return s, nil
+	}
+	v, err := runJQFirst(ctx, s, i)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expression %s must result in a string, number or bool, got %T", s, v)
+	}
+}
+
+// getOwnerUID returns the uid of the object the ownedBy expression results in
+func getOwnerUID(ctx context.Context, s string, i input.Input) (types.UID, error) {
+	v, err := runJQFirst(ctx, s, i)
+	if err != nil {
+		return "", err
+	}
+	owner, ok := v.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("expression %s must result in an object, got %T", s, v)
+	}
+	uid, _, err := unstructured.NestedString(owner, "metadata", "uid")
+	if err != nil {
+		return "", err
+	}
+	if uid == "" {
+		return "", fmt.Errorf("expression %s must result in an object with a uid", s)
+	}
+	return types.UID(uid), nil
+}
+
+// runJQFirst returns the first result of a jq expression
+func runJQFirst(ctx context.Context, s string, i input.Input) (any, error) {
+	v, err := runJQ(ctx, s, i)
+	if err != nil {
+		return nil, err
+	}
+	// runJQ returns all the results of the expression
+	if l, ok := v.([]any); ok {
+		if len(l) == 0 {
+			return nil, fmt.Errorf("expression %s has no result", s)
+		}
+		v = l[0]
+	}
+	return v, nil
 }
@@ -0,0 +1,201 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/yndd/lcnc-runtime/pkg/exec/input"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pagedReader serves the objects in pages of the requested limit and records
+// the list options of every call
+type pagedReader struct {
+	client.Client
+	objects []string
+	lists   []*client.ListOptions
+}
+
+func (r *pagedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	o := &client.ListOptions{}
+	o.ApplyOptions(opts)
+	r.lists = append(r.lists, o)
+
+	start := 0
+	if o.Continue != "" {
+		var err error
+		if start, err = strconv.Atoi(o.Continue); err != nil {
+			return fmt.Errorf("invalid continue token %q", o.Continue)
+		}
+	}
+	end := len(r.objects)
+	if o.Limit > 0 && start+int(o.Limit) < end {
+		end = start + int(o.Limit)
+	}
+
+	ul := list.(*unstructured.UnstructuredList)
+	for _, name := range r.objects[start:end] {
+		u := unstructured.Unstructured{}
+		u.SetName(name)
+		ul.Items = append(ul.Items, u)
+	}
+	if end < len(r.objects) {
+		ul.SetContinue(strconv.Itoa(end))
+	}
+	return nil
+}
+
+func newObjectNames(n int) []string {
+	names := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		names = append(names, fmt.Sprintf("obj-%d", i))
+	}
+	return names
+}
+
+func getNames(items []unstructured.Unstructured) []string {
+	names := make([]string, 0, len(items))
+	for _, u := range items {
+		names = append(names, u.GetName())
+	}
+	return names
+}
+
+var _ = Describe("Functions", func() {
+	Describe("resolveSelectorValue", func() {
+		var i input.Input
+
+		BeforeEach(func() {
+			i = input.New()
+			i.AddEntry("cr", map[string]any{
+				"metadata": map[string]any{"name": "test", "namespace": "default", "uid": "1234"},
+				"spec":     map[string]any{"replicas": 3, "scale": 1.5, "enabled": true},
+			})
+		})
+
+		It("should return a literal value", func() {
+			Expect(resolveSelectorValue(context.Background(), "web", i)).To(Equal("web"))
+		})
+
+		It("should resolve a string", func() {
+			Expect(resolveSelectorValue(context.Background(), "$cr.metadata.name", i)).To(Equal("test"))
+		})
+
+		It("should resolve an expression with leading spaces", func() {
+			Expect(resolveSelectorValue(context.Background(), " $cr.metadata.namespace", i)).To(Equal("default"))
+		})
+
+		It("should resolve a number", func() {
+			Expect(resolveSelectorValue(context.Background(), "$cr.spec.replicas", i)).To(Equal("3"))
+			Expect(resolveSelectorValue(context.Background(), "$cr.spec.scale", i)).To(Equal("1.5"))
+		})
+
+		It("should resolve a bool", func() {
+			Expect(resolveSelectorValue(context.Background(), "$cr.spec.enabled", i)).To(Equal("true"))
+		})
+
+		It("should fail for an object", func() {
+			_, err := resolveSelectorValue(context.Background(), "$cr.metadata", i)
+			Expect(err).To(MatchError(ContainSubstring("must result in a string, number or bool")))
+		})
+
+		It("should fail for a null value", func() {
+			_, err := resolveSelectorValue(context.Background(), "$cr.metadata.generation", i)
+			Expect(err).To(MatchError(ContainSubstring("must result in a string, number or bool")))
+		})
+	})
+
+	Describe("getOwnerUID", func() {
+		var i input.Input
+
+		BeforeEach(func() {
+			i = input.New()
+			i.AddEntry("cr", map[string]any{
+				"metadata": map[string]any{"name": "test", "uid": "1234"},
+			})
+		})
+
+		It("should return the uid of the owner", func() {
+			Expect(getOwnerUID(context.Background(), "$cr", i)).To(BeEquivalentTo("1234"))
+		})
+
+		It("should fail when the owner is not an object", func() {
+			_, err := getOwnerUID(context.Background(), "$cr.metadata.name", i)
+			Expect(err).To(MatchError(ContainSubstring("must result in an object")))
+		})
+
+		It("should fail when the owner has no uid", func() {
+			_, err := getOwnerUID(context.Background(), "$cr.metadata", i)
+			Expect(err).To(MatchError(ContainSubstring("must result in an object with a uid")))
+		})
+	})
+
+	Describe("query list", func() {
+		var (
+			live  *pagedReader
+			cache *pagedReader
+			gvk   *schema.GroupVersionKind
+		)
+
+		BeforeEach(func() {
+			live = &pagedReader{objects: newObjectNames(listPageSize*2 + 1)}
+			cache = &pagedReader{objects: newObjectNames(3)}
+			gvk = &schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+		})
+
+		It("should read from the cache", func() {
+			r := &query{client: live, cache: cache}
+			items, err := r.list(context.Background(), gvk, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getNames(items)).To(Equal(cache.objects))
+			Expect(cache.lists).To(HaveLen(1))
+			Expect(cache.lists[0].Limit).To(BeZero())
+			Expect(live.lists).To(BeEmpty())
+		})
+
+		It("should read live in pages when live is set", func() {
+			r := &query{client: live, cache: cache, live: true}
+			items, err := r.list(context.Background(), gvk, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getNames(items)).To(Equal(live.objects))
+			Expect(cache.lists).To(BeEmpty())
+			Expect(live.lists).To(HaveLen(3))
+			Expect(live.lists[0].Continue).To(BeEmpty())
+			Expect(live.lists[1].Continue).To(Equal(strconv.Itoa(listPageSize)))
+			Expect(live.lists[2].Continue).To(Equal(strconv.Itoa(listPageSize * 2)))
+			for _, o := range live.lists {
+				Expect(o.Limit).To(Equal(int64(listPageSize)))
+			}
+		})
+
+		It("should read live when there is no cache", func() {
+			r := &query{client: live}
+			items, err := r.list(context.Background(), gvk, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(items).To(HaveLen(len(live.objects)))
+			Expect(live.lists).To(HaveLen(3))
+		})
+
+		It("should read live with a field selector", func() {
+			r := &query{client: live, cache: cache, fieldSelector: map[string]string{"metadata.name": "obj-0"}}
+			_, err := r.list(context.Background(), gvk, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.lists).To(BeEmpty())
+			Expect(live.lists).To(HaveLen(3))
+		})
+
+		It("should pass the list options to every page", func() {
+			r := &query{client: live, live: true}
+			_, err := r.list(context.Background(), gvk, []client.ListOption{client.InNamespace("default")})
+			Expect(err).NotTo(HaveOccurred())
+			for _, o := range live.lists {
+				Expect(o.Namespace).To(Equal("default"))
+			}
+		})
+	})
+})
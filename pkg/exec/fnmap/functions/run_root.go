@@ -6,6 +6,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -56,10 +57,16 @@ func (r *root) WithRootVertexName(name string) {}
 
 func (r *root) WithClient(client client.Client) {}
 
+func (r *root) WithCache(cache client.Reader) {}
+
 func (r *root) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *root) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *root) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *root) WithMaxWorkers(n int) {}
+
 func (r *root) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	// Here we prepare the input we get from the runtime
 	// e.g. DAG, outputs/outputInfo (internal/GVK/etc), fnConfig parameters, etc etc
@@ -10,6 +10,7 @@ import (
 	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
 	"github.com/itchyny/gojq"
 	"github.com/yndd/lcnc-runtime/pkg/exec/fnmap"
+	"github.com/yndd/lcnc-runtime/pkg/exec/fnruntime"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
 	"github.com/yndd/lcnc-runtime/pkg/exec/result"
@@ -70,10 +71,16 @@ func (r *slice) WithRootVertexName(name string) {}
 
 func (r *slice) WithClient(client client.Client) {}
 
+func (r *slice) WithCache(cache client.Reader) {}
+
 func (r *slice) WithFnMap(fnMap fnmap.FuncMap) {}
 
 func (r *slice) WithServiceClients(map[schema.GroupVersionKind]svcclient.ServiceClient) {}
 
+func (r *slice) WithRunnerOptions(opts fnruntime.RunnerOptions) {}
+
+func (r *slice) WithMaxWorkers(n int) {}
+
 func (r *slice) Run(ctx context.Context, vertexContext *rtdag.VertexContext, i input.Input) (output.Output, error) {
 	r.l.Info("run", "vertexName", vertexContext.VertexName, "input", i.Get(), "expression", r.value)
 	// Here we prepare the input we get from the runtime
@@ -105,9 +112,10 @@ func (r *slice) getFinalResult() (output.Output, error) {
 			return o, err
 		}
 		o.AddEntry(varName, &output.OutputInfo{
-			Internal: oi.Internal,
-			GVK:      oi.GVK,
-			Data:     r.output,
+			Internal:       oi.Internal,
+			GVK:            oi.GVK,
+			ForceConflicts: oi.ForceConflicts,
+			Data:           r.output,
 		})
 	}
 	return o, nil
@@ -142,7 +150,7 @@ func (r *slice) run(ctx context.Context, i input.Input) (any, error) {
 		return nil, err
 	}
 
-	iter := code.Run(nil, varValues...)
+	iter := code.RunWithContext(ctx, nil, varValues...)
 	v, ok := iter.Next()
 	if !ok {
 		err := errors.New("no value")
@@ -0,0 +1,13 @@
+package fnruntime
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFnruntime(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fnruntime Suite")
+}
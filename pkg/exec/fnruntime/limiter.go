@@ -0,0 +1,53 @@
+package fnruntime
+
+import (
+	"context"
+)
+
+// DefaultMaxConcurrentContainerFns is the default number of container
+// functions that run concurrently in the process
+const DefaultMaxConcurrentContainerFns = 4
+
+// containerFnLimiter caps the number of container functions that run
+// concurrently across all the reconciles of all the controllers
+var containerFnLimiter = newLimiter(DefaultMaxConcurrentContainerFns)
+
+// SetMaxConcurrentContainerFns sets the number of container functions that
+// run concurrently in the process, a value <= 0 removes the limit.
+// It should be called before any function runs.
+func SetMaxConcurrentContainerFns(n int) {
+	containerFnLimiter = newLimiter(n)
+}
+
+type limiter struct {
+	slots chan struct{}
+}
+
+func newLimiter(n int) *limiter {
+	if n <= 0 {
+		return nil
+	}
+	return &limiter{
+		slots: make(chan struct{}, n),
+	}
+}
+
+// acquire waits till a slot is available or the context is cancelled
+func (r *limiter) acquire(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	select {
+	case r.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *limiter) release() {
+	if r == nil {
+		return
+	}
+	<-r.slots
+}
@@ -45,6 +45,10 @@ type RunnerOptions struct {
 	// enabled explicitly.
 	AllowWasm bool
 
+	// WasmMaxMemoryPages limits the memory of a wasm function in wasm pages
+	// of 64KiB, when not set the memory is limited to 64MiB.
+	WasmMaxMemoryPages uint32
+
 	// ResolveToImage will resolve a partial image to a fully-qualified one
 	ResolveToImage ImageResolveFunc
 }
@@ -81,6 +85,36 @@ func NewRunner(
 	r := &runner{
 		opts: opts,
 	}
+	// wasm functions run in process, the image or exec refer to a local
+	// OCI layout directory or wasm module
+	if fnc.Type == ctrlcfgv1.WasmType {
+		if !opts.AllowWasm {
+			return nil, fmt.Errorf("wasm functions are not allowed, they need to be enabled explicitly")
+		}
+		if opts.Kind == FunctionKindService {
+			return nil, fmt.Errorf("service not supported with wasm")
+		}
+		path := fnc.Executor.Exec
+		if fnc.Executor.Image != "" {
+			path = fnc.Executor.Image
+		}
+		if path == "" {
+			return nil, fmt.Errorf("must specify `exec` or `image` to execute a wasm function")
+		}
+		wasmFn := &WasmFn{
+			Path:           path,
+			MaxMemoryPages: opts.WasmMaxMemoryPages,
+			FnResult: &fnresultv1.Result{
+				Image:    fnc.Executor.Image,
+				ExecPath: fnc.Executor.Exec,
+			},
+		}
+		if fnc.Timeout != nil {
+			wasmFn.Timeout = fnc.Timeout.Duration
+		}
+		r.fnRunner = wasmFn
+		return r, nil
+	}
 	if fnc.Executor.Image != "" {
 		// resolve partial image
 		img, err := opts.ResolveToImage(ctx, fnc.Executor.Image)
@@ -96,7 +130,6 @@ func NewRunner(
 
 	switch {
 	case fnc.Executor.Image != "":
-		// TODO WASM
 		switch opts.Kind {
 		case FunctionKindService:
 			servicePort := strconv.Itoa(r.opts.ServicePort)
@@ -129,7 +162,6 @@ func NewRunner(
 		if opts.Kind == FunctionKindService {
 			return nil, fmt.Errorf("service not supported with exec")
 		}
-		// TODO WASM
 		var execArgs []string
 		// assuming exec here
 		s, err := shlex.Split(fnc.Executor.Exec)
@@ -177,6 +209,16 @@ func (r *runner) Run(ctx context.Context, rCtx *fn.ResourceContext) (*fn.Resourc
 
 		fmt.Printf("rctx before fn Execution:\n%s\n", in.String())
 
+		// the number of concurrently running containers is capped
+		// for the whole process
+		if _, ok := r.fnRunner.(*ContainerFn); ok {
+			l := containerFnLimiter
+			if err := l.acquire(ctx); err != nil {
+				return nil, fmt.Errorf("fn run failed waiting for a container slot: %s", err.Error())
+			}
+			defer l.release()
+		}
+
 		// call the specific implementation of run (container, exec or wasm)
 		ex := r.fnRunner.FnRun(ctx, in, out)
 		if ex != nil {
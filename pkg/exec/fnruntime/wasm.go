@@ -0,0 +1,245 @@
+package fnruntime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	fnresultv1 "github.com/yndd/lcnc-runtime/pkg/api/fnresult/v1"
+	"github.com/yndd/lcnc-runtime/pkg/internal/printer"
+)
+
+const (
+	// defaultWasmMaxMemoryPages limits the memory of a wasm function to
+	// 64MiB, a wasm page is 64KiB
+	defaultWasmMaxMemoryPages uint32 = 1024
+	defaultWasmTimeout               = 1 * time.Minute
+
+	ociLayoutFile  = "oci-layout"
+	ociIndexFile   = "index.json"
+	ociBlobsDir    = "blobs"
+	ociRefNameAnno = "org.opencontainers.image.ref.name"
+	// wasmLayerMediaType is the media type of a wasm module layer as
+	// used by the wasm OCI artifact conventions
+	wasmLayerMediaType = "application/vnd.wasm.content.layer.v1+wasm"
+)
+
+// WasmFn runs a wasm function in process with a pure go runtime. The
+// input is streamed to the module over WASI stdin and the output is read
+// from WASI stdout.
+type WasmFn struct {
+	// Path is the path of the wasm module or of an OCI layout directory
+	// holding the module. A tag in the OCI layout can be selected with
+	// <path>:<tag>, if not supplied the layout should hold a single image.
+	Path string
+	// The wasm function will be stopped after this timeout, it is set from
+	// the timeout of the function. The default value is 1 minute.
+	Timeout time.Duration
+	// MaxMemoryPages limits the memory of the wasm function in wasm pages
+	// of 64KiB. The default value is 1024 pages or 64MiB.
+	MaxMemoryPages uint32
+	// FnResult is used to store the information about the result from
+	// the function.
+	FnResult *fnresultv1.Result
+}
+
+func (f *WasmFn) SvcRun(ctx context.Context) error {
+	return fmt.Errorf("service not supported with wasm")
+}
+
+// FnRun runs the wasm module which reads the input from r and writes the
+// output to w.
+func (f *WasmFn) FnRun(ctx context.Context, r io.Reader, w io.Writer) error {
+	b, err := loadWasmModule(f.Path)
+	if err != nil {
+		return err
+	}
+
+	maxMemoryPages := defaultWasmMaxMemoryPages
+	if f.MaxMemoryPages != 0 {
+		maxMemoryPages = f.MaxMemoryPages
+	}
+	// the runtime closes the module when the context is done, such that a
+	// module that does not call the host, e.g. a cpu bound loop, is also
+	// stopped on timeout
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(maxMemoryPages).
+		WithCloseOnContextDone(true))
+	defer rt.Close(context.Background())
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		return fmt.Errorf("cannot instantiate wasi: %w", err)
+	}
+	compiled, err := rt.CompileModule(ctx, b)
+	if err != nil {
+		return fmt.Errorf("cannot compile wasm module %s: %w", f.Path, err)
+	}
+
+	errSink := bytes.Buffer{}
+	// the output is buffered such that a module that fails or times out
+	// does not write partial output
+	outSink := bytes.Buffer{}
+	cfg := wazero.NewModuleConfig().
+		WithName(filepath.Base(f.Path)).
+		WithArgs(filepath.Base(f.Path)).
+		WithStdin(r).
+		WithStdout(&outSink).
+		WithStderr(&errSink).
+		WithSysWalltime().
+		WithSysNanotime()
+
+	// setup wasm run timeout, the compilation is not part of the run
+	timeout := defaultWasmTimeout
+	if f.Timeout != 0 {
+		timeout = f.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the module runs to completion when it is instantiated
+	_, err = rt.InstantiateModule(ctx, compiled, cfg)
+	if ctx.Err() != nil {
+		return fmt.Errorf("wasm function %s stopped: %w", f.Path, ctx.Err())
+	}
+	if err != nil {
+		var exitErr *sys.ExitError
+		if !goerrors.As(err, &exitErr) {
+			return fmt.Errorf("unexpected function error: %w", err)
+		}
+		if exitErr.ExitCode() != 0 {
+			return &ExecError{
+				OriginalErr:    exitErr,
+				ExitCode:       int(exitErr.ExitCode()),
+				Stderr:         errSink.String(),
+				TruncateOutput: printer.TruncateOutput,
+			}
+		}
+	}
+
+	if errSink.Len() > 0 {
+		f.FnResult.Stderr = errSink.String()
+	}
+	_, err = io.Copy(w, &outSink)
+	return err
+}
+
+// loadWasmModule reads the wasm module from a file or from an OCI layout
+// directory
+func loadWasmModule(path string) ([]byte, error) {
+	layoutDir, tag := path, ""
+	fi, err := os.Stat(layoutDir)
+	if err != nil {
+		// the path might be <oci layout dir>:<tag>
+		idx := strings.LastIndex(path, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("cannot load wasm module: %w", err)
+		}
+		layoutDir, tag = path[:idx], path[idx+1:]
+		if fi, err = os.Stat(layoutDir); err != nil {
+			return nil, fmt.Errorf("cannot load wasm module: %w", err)
+		}
+	}
+	if !fi.IsDir() {
+		return os.ReadFile(layoutDir)
+	}
+	return loadWasmModuleFromOCILayout(layoutDir, tag)
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// loadWasmModuleFromOCILayout reads the wasm layer of the image in the OCI
+// layout directory, the image is selected by its tag when there are
+// multiple images in the layout
+func loadWasmModuleFromOCILayout(dir, tag string) ([]byte, error) {
+	if _, err := os.Stat(filepath.Join(dir, ociLayoutFile)); err != nil {
+		return nil, fmt.Errorf("%s is not an OCI layout directory: %w", dir, err)
+	}
+	idx := &ociIndex{}
+	if err := readOCIJSON(filepath.Join(dir, ociIndexFile), idx); err != nil {
+		return nil, err
+	}
+	var manifestDesc *ociDescriptor
+	for i, d := range idx.Manifests {
+		if tag == "" || d.Annotations[ociRefNameAnno] == tag {
+			if manifestDesc != nil {
+				return nil, fmt.Errorf("OCI layout %s holds multiple images, select one with <path>:<tag>", dir)
+			}
+			manifestDesc = &idx.Manifests[i]
+		}
+	}
+	if manifestDesc == nil {
+		return nil, fmt.Errorf("no image found in OCI layout %s with tag %q", dir, tag)
+	}
+	manifestPath, err := getOCIBlobPath(dir, manifestDesc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	m := &ociManifest{}
+	if err := readOCIJSON(manifestPath, m); err != nil {
+		return nil, err
+	}
+	var layerDesc *ociDescriptor
+	for i, l := range m.Layers {
+		if l.MediaType == wasmLayerMediaType {
+			layerDesc = &m.Layers[i]
+			break
+		}
+	}
+	// an image with a single layer of another media type is assumed to be
+	// the wasm module
+	if layerDesc == nil && len(m.Layers) == 1 {
+		layerDesc = &m.Layers[0]
+	}
+	if layerDesc == nil {
+		return nil, fmt.Errorf("no wasm layer found in image %s of OCI layout %s", manifestDesc.Digest, dir)
+	}
+	layerPath, err := getOCIBlobPath(dir, layerDesc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(layerPath)
+}
+
+func readOCIJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot unmarshal %s: %w", path, err)
+	}
+	return nil
+}
+
+// getOCIBlobPath returns the path of the blob with the digest
+// <algorithm>:<encoded> in the OCI layout directory
+func getOCIBlobPath(dir, digest string) (string, error) {
+	split := strings.SplitN(digest, ":", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" || strings.ContainsAny(split[1], `/\.`) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(dir, ociBlobsDir, split[0], split[1]), nil
+}
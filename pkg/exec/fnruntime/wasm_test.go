@@ -0,0 +1,178 @@
+package fnruntime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	fnresultv1 "github.com/yndd/lcnc-runtime/pkg/api/fnresult/v1"
+)
+
+var (
+	// wasmHeader is the magic and version of a wasm module followed by
+	// a type section with a single func type without params and results
+	wasmHeader = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	}
+	// wasmStart is a function section with a single function and an
+	// export section which exports the function as _start
+	wasmStart = []byte{
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+	}
+	// noopWasm is a module with a _start function that returns immediately
+	noopWasm = wasmModule(wasmStart, []byte{0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b})
+	// loopWasm is a module with a _start function that loops forever
+	// without calling the host
+	loopWasm = wasmModule(wasmStart, []byte{0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b})
+	// memoryWasm is a module with a memory of 2 pages
+	memoryWasm = wasmModule([]byte{0x05, 0x03, 0x01, 0x00, 0x02})
+)
+
+func wasmModule(sections ...[]byte) []byte {
+	b := append([]byte{}, wasmHeader...)
+	for _, s := range sections {
+		b = append(b, s...)
+	}
+	return b
+}
+
+func writeFile(path string, b []byte) {
+	Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+	Expect(os.WriteFile(path, b, 0644)).To(Succeed())
+}
+
+// writeBlob writes the blob to the OCI layout and returns its descriptor
+func writeBlob(dir, mediaType string, b []byte) ociDescriptor {
+	sum := sha256.Sum256(b)
+	encoded := hex.EncodeToString(sum[:])
+	writeFile(filepath.Join(dir, ociBlobsDir, "sha256", encoded), b)
+	return ociDescriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + encoded,
+		Size:      int64(len(b)),
+	}
+}
+
+// writeOCILayout writes an OCI layout with an image per tag, the image
+// holds the wasm module in a wasm layer
+func writeOCILayout(dir string, modules map[string][]byte) {
+	writeFile(filepath.Join(dir, ociLayoutFile), []byte(`{"imageLayoutVersion": "1.0.0"}`))
+	idx := &ociIndex{}
+	for tag, module := range modules {
+		m := &ociManifest{Layers: []ociDescriptor{
+			writeBlob(dir, "application/vnd.oci.image.config.v1+json", []byte("{}")),
+			writeBlob(dir, wasmLayerMediaType, module),
+		}}
+		b, err := json.Marshal(m)
+		Expect(err).NotTo(HaveOccurred())
+		d := writeBlob(dir, "application/vnd.oci.image.manifest.v1+json", b)
+		d.Annotations = map[string]string{ociRefNameAnno: tag}
+		idx.Manifests = append(idx.Manifests, d)
+	}
+	b, err := json.Marshal(idx)
+	Expect(err).NotTo(HaveOccurred())
+	writeFile(filepath.Join(dir, ociIndexFile), b)
+}
+
+var _ = Describe("Wasm", func() {
+	Describe("loadWasmModule", func() {
+		It("should load a wasm module file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "fn.wasm")
+			writeFile(path, noopWasm)
+			b, err := loadWasmModule(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(b).To(Equal(noopWasm))
+		})
+
+		It("should load the wasm layer of the single image in an OCI layout", func() {
+			dir := GinkgoT().TempDir()
+			writeOCILayout(dir, map[string][]byte{"v1": noopWasm})
+			b, err := loadWasmModule(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(b).To(Equal(noopWasm))
+		})
+
+		It("should load the wasm layer of the image with the tag in an OCI layout", func() {
+			dir := GinkgoT().TempDir()
+			writeOCILayout(dir, map[string][]byte{"v1": noopWasm, "v2": loopWasm})
+			b, err := loadWasmModule(dir + ":v2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(b).To(Equal(loopWasm))
+		})
+
+		It("should fail when the OCI layout holds multiple images and no tag is supplied", func() {
+			dir := GinkgoT().TempDir()
+			writeOCILayout(dir, map[string][]byte{"v1": noopWasm, "v2": loopWasm})
+			_, err := loadWasmModule(dir)
+			Expect(err).To(MatchError(ContainSubstring("holds multiple images")))
+		})
+
+		It("should fail when the tag is not in the OCI layout", func() {
+			dir := GinkgoT().TempDir()
+			writeOCILayout(dir, map[string][]byte{"v1": noopWasm})
+			_, err := loadWasmModule(dir + ":v3")
+			Expect(err).To(MatchError(ContainSubstring(`no image found in OCI layout`)))
+		})
+
+		It("should fail when the directory is not an OCI layout", func() {
+			_, err := loadWasmModule(GinkgoT().TempDir())
+			Expect(err).To(MatchError(ContainSubstring("is not an OCI layout directory")))
+		})
+
+		It("should reject a digest that escapes the blobs directory", func() {
+			_, err := getOCIBlobPath("/layout", "sha256:../../etc")
+			Expect(err).To(MatchError(ContainSubstring("invalid digest")))
+		})
+	})
+
+	Describe("FnRun", func() {
+		It("should run a wasm module from an OCI layout", func() {
+			dir := GinkgoT().TempDir()
+			writeOCILayout(dir, map[string][]byte{"v1": noopWasm})
+			f := &WasmFn{
+				Path:     dir + ":v1",
+				FnResult: &fnresultv1.Result{},
+			}
+			w := &bytes.Buffer{}
+			Expect(f.FnRun(context.Background(), strings.NewReader(""), w)).To(Succeed())
+			Expect(w.Len()).To(Equal(0))
+		})
+
+		It("should stop a cpu bound wasm module on timeout", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "loop.wasm")
+			writeFile(path, loopWasm)
+			f := &WasmFn{
+				Path:     path,
+				Timeout:  100 * time.Millisecond,
+				FnResult: &fnresultv1.Result{},
+			}
+			start := time.Now()
+			err := f.FnRun(context.Background(), strings.NewReader(""), &bytes.Buffer{})
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("wasm function " + path + " stopped")))
+			Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		})
+
+		It("should limit the memory of a wasm module", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "memory.wasm")
+			writeFile(path, memoryWasm)
+			f := &WasmFn{
+				Path:     path,
+				FnResult: &fnresultv1.Result{},
+			}
+			Expect(f.FnRun(context.Background(), strings.NewReader(""), &bytes.Buffer{})).To(Succeed())
+			f.MaxMemoryPages = 1
+			Expect(f.FnRun(context.Background(), strings.NewReader(""), &bytes.Buffer{})).To(HaveOccurred())
+		})
+	})
+})
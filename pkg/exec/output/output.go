@@ -14,6 +14,8 @@ type Output interface {
 	GetData(k string) any
 	Print()
 	GetFinalOutput() []any
+	// GetFinalOutputInfo returns the output info of the external outputs
+	GetFinalOutputInfo() []*OutputInfo
 	GetConditionedOutput() map[string]any
 }
 
@@ -21,7 +23,10 @@ type OutputInfo struct {
 	Internal    bool
 	Conditioned bool
 	GVK         *schema.GroupVersionKind
-	Data        any
+	// ForceConflicts takes over the fields of the resources of the output
+	// that are managed by another field manager when they are applied
+	ForceConflicts bool
+	Data           any
 }
 
 func New() Output {
@@ -85,6 +90,17 @@ func (r *output) Print() {
 
 func (r *output) GetFinalOutput() []any {
 	fo := []any{}
+	for _, oi := range r.GetFinalOutputInfo() {
+		switch d := oi.Data.(type) {
+		case []any:
+			fo = append(fo, d...)
+		}
+	}
+	return fo
+}
+
+func (r *output) GetFinalOutputInfo() []*OutputInfo {
+	fo := []*OutputInfo{}
 	for _, v := range r.o.Get() {
 		oi, ok := v.(*OutputInfo)
 		if !ok {
@@ -92,10 +108,7 @@ func (r *output) GetFinalOutput() []any {
 			continue
 		}
 		if !oi.Internal {
-			switch d := oi.Data.(type) {
-			case []any:
-				fo = append(fo, d...)
-			}
+			fo = append(fo, oi)
 		}
 	}
 	return fo
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/kv"
+)
+
+// NewScope returns the output of a nested scope e.g. a function block.
+// The variables of the scope are only visible within the scope and take
+// precedence over the variables of the parent output. Entries are added to
+// the local output, such that every execution of the scope e.g. an iteration
+// of a range keeps its own output, the owner of the scope decides how the
+// local output is handed to the parent output when the scope ends.
+func NewScope(parent, local Output, vars map[string]any) Output {
+	s := &scope{
+		parent: parent,
+		local:  local,
+		vars:   kv.New(),
+	}
+	for varName, v := range vars {
+		s.vars.AddEntry(varName, &OutputInfo{
+			Internal: true,
+			Data:     v,
+		})
+	}
+	return s
+}
+
+type scope struct {
+	parent Output
+	local  Output
+	vars   kv.KV
+}
+
+func (r *scope) AddEntry(k string, v any) {
+	r.local.AddEntry(k, v)
+}
+
+func (r *scope) Add(o kv.KV) {
+	r.local.Add(o)
+}
+
+func (r *scope) Get() map[string]any {
+	d := r.parent.Get()
+	for k, v := range r.local.Get() {
+		d[k] = v
+	}
+	for k, v := range r.vars.Get() {
+		d[k] = v
+	}
+	return d
+}
+
+func (r *scope) GetValue(k string) any {
+	if v := r.vars.GetValue(k); v != nil {
+		return v
+	}
+	if v := r.local.GetValue(k); v != nil {
+		return v
+	}
+	return r.parent.GetValue(k)
+}
+
+func (r *scope) Length() int {
+	return len(r.Get())
+}
+
+func (r *scope) GetData(k string) any {
+	v := r.GetValue(k)
+	oi, ok := v.(*OutputInfo)
+	if !ok {
+		return nil
+	}
+	return oi.Data
+}
+
+// used for debugging purposes
+func (r *scope) Print() {
+	for varName := range r.vars.Get() {
+		fmt.Printf("  scope varName: %s\n", varName)
+	}
+	r.local.Print()
+	r.parent.Print()
+}
+
+// the variables of the scope are internal, so the final and conditioned
+// output is provided by the parent and the local output
+func (r *scope) GetFinalOutput() []any {
+	return append(r.parent.GetFinalOutput(), r.local.GetFinalOutput()...)
+}
+
+func (r *scope) GetFinalOutputInfo() []*OutputInfo {
+	return append(r.parent.GetFinalOutputInfo(), r.local.GetFinalOutputInfo()...)
+}
+
+func (r *scope) GetConditionedOutput() map[string]any {
+	co := r.parent.GetConditionedOutput()
+	for k, v := range r.local.GetConditionedOutput() {
+		co[k] = v
+	}
+	return co
+}
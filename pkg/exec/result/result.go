@@ -2,8 +2,10 @@ package result
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/yndd/lcnc-runtime/pkg/ccutils/executor"
 	"github.com/yndd/lcnc-runtime/pkg/ccutils/slice"
 	"github.com/yndd/lcnc-runtime/pkg/exec/input"
 	"github.com/yndd/lcnc-runtime/pkg/exec/output"
@@ -12,6 +14,17 @@ import (
 type Result interface {
 	slice.Slice
 	Print()
+	// Success returns true when the execution recorded in the result
+	// succeeded
+	Success() bool
+	// Failure returns the first vertex that failed in the order of
+	// Failures. Nil is returned when no vertex failed.
+	Failure() *ResultInfo
+	// Failures returns the vertices that failed sorted by vertex name, the
+	// failures in a block are returned instead of the block itself. The
+	// results are recorded in the order the vertices finish, so they are
+	// sorted to report the same failure across runs.
+	Failures() []*ResultInfo
 }
 
 type ExecType string
@@ -22,16 +35,30 @@ const (
 )
 
 type ResultInfo struct {
-	Type        ExecType
-	ExecName    string
-	VertexName  string
-	StartTime   time.Time
-	EndTime     time.Time
-	Input       input.Input
-	Output      output.Output
+	Type       ExecType
+	ExecName   string
+	VertexName string
+	StartTime  time.Time
+	EndTime    time.Time
+	Input      input.Input
+	Output     output.Output
+	// Outcome is the outcome of the vertex, Success is false when the
+	// vertex failed and Reason explains a failure or a skip
+	Outcome     executor.VertexOutcome
 	Success     bool
 	Reason      string
 	BlockResult Result
+	// Attempts records every attempt to run the function, a function is
+	// attempted more than once when it has retries
+	Attempts []*AttemptInfo
+}
+
+type AttemptInfo struct {
+	Attempt   int
+	StartTime time.Time
+	EndTime   time.Time
+	Success   bool
+	Reason    string
 }
 
 func New() Result {
@@ -56,6 +83,47 @@ func (r *result) Length() int {
 	return r.r.Length()
 }
 
+func (r *result) Success() bool {
+	for _, v := range r.r.Get() {
+		ri, ok := v.(*ResultInfo)
+		if ok && ri.Type == ExecRootType && ri.VertexName == "total" {
+			return ri.Success
+		}
+	}
+	return false
+}
+
+func (r *result) Failure() *ResultInfo {
+	if f := r.Failures(); len(f) != 0 {
+		return f[0]
+	}
+	return nil
+}
+
+func (r *result) Failures() []*ResultInfo {
+	f := []*ResultInfo{}
+	for _, v := range r.r.Get() {
+		ri, ok := v.(*ResultInfo)
+		if !ok || ri.Success || (ri.Type == ExecRootType && ri.VertexName == "total") {
+			continue
+		}
+		if ri.BlockResult != nil {
+			if bf := ri.BlockResult.Failures(); len(bf) != 0 {
+				f = append(f, bf...)
+				continue
+			}
+		}
+		f = append(f, ri)
+	}
+	sort.SliceStable(f, func(i, j int) bool {
+		if f[i].VertexName != f[j].VertexName {
+			return f[i].VertexName < f[j].VertexName
+		}
+		return f[i].ExecName < f[j].ExecName
+	})
+	return f
+}
+
 func (r *result) Print() {
 	totalSuccess := true
 	var totalDuration time.Duration
@@ -72,14 +140,28 @@ func (r *result) Print() {
 				totalSuccess = false
 				s = "NOK"
 			}
-			fmt.Printf("  result order: %d exec: %s vertex: %s, duration %s, success: %s, reason: %s\n",
+			if ri.Outcome == executor.VertexSkipped {
+				s = "SKIP"
+			}
+			fmt.Printf("  result order: %d exec: %s vertex: %s, duration %s, success: %s, attempts: %d, reason: %s\n",
 				i,
 				ri.ExecName,
 				ri.VertexName,
 				ri.EndTime.Sub(ri.StartTime),
 				s,
+				len(ri.Attempts),
 				ri.Reason,
 			)
+			if len(ri.Attempts) > 1 {
+				for _, ai := range ri.Attempts {
+					fmt.Printf("    attempt: %d, duration %s, success: %t, reason: %s\n",
+						ai.Attempt,
+						ai.EndTime.Sub(ai.StartTime),
+						ai.Success,
+						ai.Reason,
+					)
+				}
+			}
 
 			if ri.BlockResult != nil {
 				ri.BlockResult.Print()
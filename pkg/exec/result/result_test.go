@@ -0,0 +1,46 @@
+package result
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Result", func() {
+	Describe("Failure", func() {
+		It("should return nil when no vertex failed", func() {
+			r := New()
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "a", Success: true})
+			Expect(r.Failure()).To(BeNil())
+		})
+
+		It("should not return the total", func() {
+			r := New()
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "total"})
+			Expect(r.Failure()).To(BeNil())
+		})
+
+		It("should return the failed vertex with the lowest name", func() {
+			r := New()
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "c", Reason: "c failed"})
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "a", Success: true})
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "b", Reason: "b failed"})
+			Expect(r.Failure()).To(HaveField("Reason", "b failed"))
+		})
+
+		It("should return the failure in a block", func() {
+			br := New()
+			br.Add(&ResultInfo{Type: ExecBlockType, ExecName: "block", VertexName: "inner", Reason: "inner failed"})
+			r := New()
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "block", Reason: "block failed", BlockResult: br})
+			Expect(r.Failure()).To(HaveField("Reason", "inner failed"))
+		})
+
+		It("should return the block when no inner vertex failed", func() {
+			br := New()
+			br.Add(&ResultInfo{Type: ExecBlockType, ExecName: "block", VertexName: "inner", Success: true})
+			r := New()
+			r.Add(&ResultInfo{Type: ExecRootType, ExecName: "root", VertexName: "block", Reason: "block failed", BlockResult: br})
+			Expect(r.Failure()).To(HaveField("Reason", "block failed"))
+		})
+	})
+})
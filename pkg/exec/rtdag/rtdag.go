@@ -2,7 +2,6 @@ package rtdag
 
 import (
 	"fmt"
-	"os"
 	"sync"
 
 	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
@@ -14,7 +13,7 @@ type RuntimeDAG interface {
 	dag.DAG
 
 	GetRootVertex() string
-	GetDependencyMap(from string)
+	GetDependencyMap(from string) error
 	PrintVertices()
 }
 
@@ -91,6 +90,10 @@ func (r *runtimeDAG) TransitiveReduction() {
 	r.d.TransitiveReduction()
 }
 
+func (r *runtimeDAG) GetCycle() []string {
+	return r.d.GetCycle()
+}
+
 func (r *VertexContext) AddReference(s string) {
 	r.m.Lock()
 	defer r.m.Unlock()
@@ -117,36 +120,40 @@ func (r *runtimeDAG) GetRootVertex() string {
 	return ""
 }
 
-func (r *runtimeDAG) GetDependencyMap(from string) {
+// GetDependencyMap prints the dependencies starting from the vertex, an error
+// is returned when an edge refers to a vertex that does not exist
+func (r *runtimeDAG) GetDependencyMap(from string) error {
 	fmt.Println("######### dependency map verteces start ###########")
 	for vertexName := range r.GetVertices() {
 		fmt.Printf("%s\n", vertexName)
 	}
 	fmt.Println("######### dependency map verteces end ###########")
 	fmt.Println("######### dependency map start ###########")
-	r.getDependencyMap(from, 0)
+	if err := r.getDependencyMap(from, 0); err != nil {
+		return err
+	}
 	fmt.Println("######### dependency map end   ###########")
+	return nil
 }
 
-func (r *runtimeDAG) getDependencyMap(from string, indent int) {
+func (r *runtimeDAG) getDependencyMap(from string, indent int) error {
 	fmt.Printf("%s:\n", from)
 	for _, upVertex := range r.GetUpVertexes(from) {
-		found := r.checkVertex(upVertex)
-		if !found {
-			fmt.Printf("upVertex %s no found in vertices\n", upVertex)
-			os.Exit(1)
+		if !r.checkVertex(upVertex) {
+			return fmt.Errorf("upVertex %s not found in vertices", upVertex)
 		}
 		fmt.Printf("-> %s\n", upVertex)
 	}
 	indent++
 	for _, downVertex := range r.GetDownVertexes(from) {
-		found := r.checkVertex(downVertex)
-		if !found {
-			fmt.Printf("upVertex %s no found in vertices\n", downVertex)
-			os.Exit(1)
+		if !r.checkVertex(downVertex) {
+			return fmt.Errorf("downVertex %s not found in vertices", downVertex)
+		}
+		if err := r.getDependencyMap(downVertex, indent); err != nil {
+			return err
 		}
-		r.getDependencyMap(downVertex, indent)
 	}
+	return nil
 }
 
 func (r *runtimeDAG) checkVertex(s string) bool {
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/henderiw-k8s-lcnc/fn-svc-sdk/pkg/svcclient"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GetClients returns a service client for each service instance, the clients
+// need to be closed by the caller
+func GetClients(s Services) (map[schema.GroupVersionKind]svcclient.ServiceClient, error) {
+	sc := map[schema.GroupVersionKind]svcclient.ServiceClient{}
+	for gvk, svcCtx := range s.Get() {
+		svcClient, err := svcclient.New(&svcclient.Config{
+			Address:  strings.Join([]string{"127.0.0.1", strconv.Itoa(svcCtx.Port)}, ":"),
+			Insecure: true,
+		})
+		if err != nil {
+			for _, c := range sc {
+				c.Close()
+			}
+			return nil, err
+		}
+		sc[gvk] = svcClient
+	}
+	return sc, nil
+}
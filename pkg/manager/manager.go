@@ -18,12 +18,14 @@ package manager
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"time"
 
 	"github.com/go-logr/logr"
 	intrec "github.com/yndd/lcnc-runtime/pkg/internal/recorder"
+	"github.com/yndd/lcnc-runtime/pkg/webhook"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
@@ -83,6 +85,9 @@ type Manager interface {
 	// lock was lost.
 	Start(ctx context.Context) error
 
+	// GetWebhookServer returns a webhook.Server
+	GetWebhookServer() *webhook.Server
+
 	// GetLogger returns this manager's logger.
 	GetLogger() logr.Logger
 
@@ -92,6 +97,11 @@ type Manager interface {
 
 // Options are the arguments for creating a new Manager.
 type Options struct {
+	// Scheme is the scheme
+	// Defaults to the kubernetes/client-go scheme.Scheme, but it's almost always better
+	// to pass your own scheme in. See the documentation in pkg/scheme for more information.
+	Scheme *runtime.Scheme
+
 	// MapperProvider provides the rest mapper used to map go types to Kubernetes APIs
 	MapperProvider func(c *rest.Config) (meta.RESTMapper, error)
 
@@ -150,6 +160,28 @@ type Options struct {
 	// Liveness probe endpoint name, defaults to "healthz"
 	LivenessEndpointName string
 
+	// Port is the port that the webhook server serves at.
+	// It is used to set webhook.Server.Port if WebhookServer is not set.
+	Port int
+	// Host is the hostname that the webhook server binds to.
+	// It is used to set webhook.Server.Host if WebhookServer is not set.
+	Host string
+
+	// CertDir is the directory that contains the server key and certificate.
+	// If not set, webhook server would look up the server key and certificate in
+	// {TempDir}/k8s-webhook-server/serving-certs. The server key and certificate
+	// must be named tls.key and tls.crt, respectively.
+	// It is used to set webhook.Server.CertDir if WebhookServer is not set.
+	CertDir string
+
+	// TLSOpts is used to allow configuring the TLS config used for the webhook server.
+	TLSOpts []func(*tls.Config)
+
+	// WebhookServer is an externally configured webhook.Server. By default,
+	// a Manager will create a default server using Port, Host, and CertDir;
+	// if this is set, the Manager will use this server instead.
+	WebhookServer *webhook.Server
+
 	// Functions to allow for a user to customize values that will be injected.
 
 	// NewCache is the function that will create the cache to be used
@@ -242,6 +274,7 @@ func New(config *rest.Config, options Options) (Manager, error) {
 	options = setOptionsDefaults(options)
 
 	cluster, err := cluster.New(config, func(clusterOptions *cluster.Options) {
+		clusterOptions.Scheme = options.Scheme
 		clusterOptions.MapperProvider = options.MapperProvider
 		clusterOptions.Logger = options.Logger
 		clusterOptions.SyncPeriod = options.SyncPeriod
@@ -298,6 +331,11 @@ func New(config *rest.Config, options Options) (Manager, error) {
 		logger:                  options.Logger,
 		elected:                 make(chan struct{}),
 		healthProbeListener:     healthProbeListener,
+		port:                    options.Port,
+		host:                    options.Host,
+		certDir:                 options.CertDir,
+		tlsOpts:                 options.TLSOpts,
+		webhookServer:           options.WebhookServer,
 		readinessEndpointName:   options.ReadinessEndpointName,
 		livenessEndpointName:    options.LivenessEndpointName,
 		gracefulShutdownTimeout: *options.GracefulShutdownTimeout,
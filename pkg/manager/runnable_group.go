@@ -5,7 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
-	//"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/yndd/lcnc-runtime/pkg/webhook"
 )
 
 var (
@@ -28,7 +29,7 @@ type runnableCheck func(ctx context.Context) bool
 // runnables handles all the runnables for a manager by grouping them accordingly to their
 // type (webhooks, caches etc.).
 type runnables struct {
-	//Webhooks       *runnableGroup
+	Webhooks       *runnableGroup
 	Caches         *runnableGroup
 	LeaderElection *runnableGroup
 	Others         *runnableGroup
@@ -37,7 +38,7 @@ type runnables struct {
 // newRunnables creates a new runnables object.
 func newRunnables(baseContext BaseContextFunc, errChan chan error) *runnables {
 	return &runnables{
-		//Webhooks:       newRunnableGroup(baseContext, errChan),
+		Webhooks:       newRunnableGroup(baseContext, errChan),
 		Caches:         newRunnableGroup(baseContext, errChan),
 		LeaderElection: newRunnableGroup(baseContext, errChan),
 		Others:         newRunnableGroup(baseContext, errChan),
@@ -57,8 +58,8 @@ func (r *runnables) Add(fn Runnable) error {
 		return r.Caches.Add(fn, func(ctx context.Context) bool {
 			return runnable.GetCache().WaitForCacheSync(ctx)
 		})
-	//case *webhook.Server:
-	//	return r.Webhooks.Add(fn, nil)
+	case *webhook.Server:
+		return r.Webhooks.Add(fn, nil)
 	case LeaderElectionRunnable:
 		if !runnable.NeedLeaderElection() {
 			fmt.Println("runnable add NON leader election")
@@ -0,0 +1,64 @@
+package meta
+
+import (
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The owner labels track the owner of a resource that cannot refer to its
+// owner with an owner reference, e.g. a cluster-scoped resource or a resource
+// in another namespace than its owner.
+const (
+	LabelKeyOwnerKind      = "lcnc.yndd.io/owner-kind"
+	LabelKeyOwnerNamespace = "lcnc.yndd.io/owner-namespace"
+	LabelKeyOwnerName      = "lcnc.yndd.io/owner-name"
+)
+
+// IsNamespaced returns true when the resources of the gvk are namespaced
+func IsNamespaced(mapper apimeta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	return m.Scope.Name() == apimeta.RESTScopeNameNamespace, nil
+}
+
+// CanOwn returns true when the owner can be referenced in the owner references
+// of a resource, a namespaced owner can only own resources in its namespace
+func CanOwn(owner metav1.Object, namespaced bool, namespace string) bool {
+	return owner.GetNamespace() == "" || (namespaced && namespace == owner.GetNamespace())
+}
+
+// AddOwnerLabels adds the labels that track the owner of the group kind to
+// the resource
+func AddOwnerLabels(o metav1.Object, owner metav1.Object, gk schema.GroupKind) error {
+	labels := map[string]string{
+		LabelKeyOwnerKind:      gk.String(),
+		LabelKeyOwnerNamespace: owner.GetNamespace(),
+		LabelKeyOwnerName:      owner.GetName(),
+	}
+	for k, v := range labels {
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return fmt.Errorf("cannot track owner with label %s: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	AddLabels(o, labels)
+	return nil
+}
+
+// GetOwnerFromLabels returns the owner of the group kind that is tracked by
+// the labels of the resource
+func GetOwnerFromLabels(o metav1.Object, gk schema.GroupKind) (types.NamespacedName, bool) {
+	l := o.GetLabels()
+	name, ok := l[LabelKeyOwnerName]
+	if !ok || l[LabelKeyOwnerKind] != gk.String() {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: l[LabelKeyOwnerNamespace], Name: name}, true
+}
@@ -0,0 +1,108 @@
+package meta
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newObject(namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+var _ = Describe("Meta", func() {
+	gk := schema.GroupKind{Group: "example.com", Kind: "App"}
+
+	Describe("IsNamespaced", func() {
+		var mapper *apimeta.DefaultRESTMapper
+
+		BeforeEach(func() {
+			mapper = apimeta.NewDefaultRESTMapper(nil)
+			mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, apimeta.RESTScopeNamespace)
+			mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+		})
+
+		It("should return true for a namespaced resource", func() {
+			Expect(IsNamespaced(mapper, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})).To(BeTrue())
+		})
+
+		It("should return false for a cluster scoped resource", func() {
+			Expect(IsNamespaced(mapper, schema.GroupVersionKind{Version: "v1", Kind: "Namespace"})).To(BeFalse())
+		})
+
+		It("should fail for an unknown resource", func() {
+			_, err := IsNamespaced(mapper, schema.GroupVersionKind{Version: "v1", Kind: "Unknown"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CanOwn", func() {
+		It("should own a resource in the namespace of the owner", func() {
+			Expect(CanOwn(newObject("default", "a"), true, "default")).To(BeTrue())
+		})
+
+		It("should not own a resource in another namespace", func() {
+			Expect(CanOwn(newObject("default", "a"), true, "other")).To(BeFalse())
+		})
+
+		It("should not own a cluster scoped resource by a namespaced owner", func() {
+			Expect(CanOwn(newObject("default", "a"), false, "")).To(BeFalse())
+		})
+
+		It("should own any resource by a cluster scoped owner", func() {
+			Expect(CanOwn(newObject("", "a"), false, "")).To(BeTrue())
+			Expect(CanOwn(newObject("", "a"), true, "other")).To(BeTrue())
+		})
+	})
+
+	Describe("AddOwnerLabels", func() {
+		It("should add the owner labels and keep the other labels", func() {
+			u := newObject("", "a")
+			u.SetLabels(map[string]string{"app": "web"})
+			Expect(AddOwnerLabels(u, newObject("default", "owner"), gk)).To(Succeed())
+			Expect(u.GetLabels()).To(Equal(map[string]string{
+				"app":                  "web",
+				LabelKeyOwnerKind:      "App.example.com",
+				LabelKeyOwnerNamespace: "default",
+				LabelKeyOwnerName:      "owner",
+			}))
+		})
+
+		It("should fail for an owner name that is not a valid label value", func() {
+			u := newObject("", "a")
+			err := AddOwnerLabels(u, newObject("default", strings.Repeat("a", 64)), gk)
+			Expect(err).To(MatchError(ContainSubstring("cannot track owner with label " + LabelKeyOwnerName)))
+			Expect(u.GetLabels()).To(BeEmpty())
+		})
+	})
+
+	Describe("GetOwnerFromLabels", func() {
+		It("should return the owner of the labels", func() {
+			u := newObject("", "a")
+			Expect(AddOwnerLabels(u, newObject("default", "owner"), gk)).To(Succeed())
+			nsn, ok := GetOwnerFromLabels(u, gk)
+			Expect(ok).To(BeTrue())
+			Expect(nsn).To(Equal(types.NamespacedName{Namespace: "default", Name: "owner"}))
+		})
+
+		It("should not return an owner without labels", func() {
+			_, ok := GetOwnerFromLabels(newObject("", "a"), gk)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should not return an owner of another group kind", func() {
+			u := newObject("", "a")
+			Expect(AddOwnerLabels(u, newObject("default", "owner"), gk)).To(Succeed())
+			_, ok := GetOwnerFromLabels(u, schema.GroupKind{Group: "example.com", Kind: "Other"})
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
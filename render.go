@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	ctrlcfgv1 "github.com/yndd/lcnc-runtime/pkg/api/controllerconfig/v1"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax"
+	"github.com/yndd/lcnc-runtime/pkg/ccsyntax/render"
+	"sigs.k8s.io/yaml"
+)
+
+// renderDAGs parses the controller config in the file and writes the
+// compiled DAGs in the requested format to the output file
+func renderDAGs(file string, f render.Format, out string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	cfg := &ctrlcfgv1.ControllerConfig{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return err
+	}
+	if cfg.Spec.Properties == nil {
+		return errors.New("a controller config must have properties")
+	}
+
+	p, result := ccsyntax.NewParser(cfg)
+	if len(result) != 0 {
+		return getResultError("syntax validation failed", result)
+	}
+	ceCtx, result := p.Parse()
+	if len(result) != 0 {
+		return getResultError("parsing failed", result)
+	}
+
+	if out == "" {
+		out = fmt.Sprintf("%s.%s", cfg.GetName(), f.GetExtension())
+	}
+	w, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return render.Render(w, render.GetGraph(ceCtx), f)
+}
+
+func getResultError(msg string, result []ccsyntax.Result) error {
+	errs := make([]string, 0, len(result))
+	for _, res := range result {
+		errs = append(errs, fmt.Sprintf("%s: %s", res.FieldPath(), res.Error))
+	}
+	return fmt.Errorf("%s: %s", msg, strings.Join(errs, "; "))
+}